@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"math/big"
 	"strings"
 
 	"github.com/ythosa/pukiclang/src/token"
@@ -11,6 +12,7 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
 }
 
 // Statement is interface for statements elements in the AST tree
@@ -28,6 +30,7 @@ type Expression interface {
 // Program is type for program - higher element of AST tree
 type Program struct {
 	Statements []Statement
+	Comments   []*Comment // comments of the source code in order, collected only if lexer emits them
 }
 
 // TokenLiteral returns token literal of the node
@@ -39,6 +42,15 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+// Pos returns position of the node in the source code
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+
+	return token.Position{}
+}
+
 // String returns string representation of the node
 func (p *Program) String() string {
 	var out bytes.Buffer
@@ -50,6 +62,27 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+// Comment is type for line or block comment of the source code, it is not part of statements
+// and can be attached to nodes by its position
+type Comment struct {
+	Token token.Token // the token.COMMENT token
+}
+
+// TokenLiteral returns token literal of the node
+func (c *Comment) TokenLiteral() string {
+	return c.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (c *Comment) Pos() token.Position {
+	return c.Token.Pos
+}
+
+// String returns string representation of the node
+func (c *Comment) String() string {
+	return c.Token.Literal
+}
+
 // LetStatement is type for let statements in the AST tree
 type LetStatement struct {
 	Token token.Token // the token.LET token
@@ -64,6 +97,11 @@ func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (ls *LetStatement) Pos() token.Position {
+	return ls.Token.Pos
+}
+
 // String returns string representation of the node
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
@@ -93,6 +131,11 @@ func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (rs *ReturnStatement) Pos() token.Position {
+	return rs.Token.Pos
+}
+
 // String returns string representation of the node
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
@@ -106,6 +149,96 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// ThrowStatement is type for throw statements in the AST tree
+type ThrowStatement struct {
+	Token token.Token // the 'throw' token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+// TokenLiteral returns token literal of the node
+func (ts *ThrowStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (ts *ThrowStatement) Pos() token.Position {
+	return ts.Token.Pos
+}
+
+// String returns string representation of the node
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
+// ExportStatement is type for let statements which export bindings from module in the AST tree
+type ExportStatement struct {
+	Token     token.Token // the 'export' token
+	Statement *LetStatement
+}
+
+func (es *ExportStatement) statementNode() {}
+
+// TokenLiteral returns token literal of the node
+func (es *ExportStatement) TokenLiteral() string {
+	return es.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (es *ExportStatement) Pos() token.Position {
+	return es.Token.Pos
+}
+
+// String returns string representation of the node
+func (es *ExportStatement) String() string {
+	return es.TokenLiteral() + " " + es.Statement.String()
+}
+
+// BreakStatement is type for break statements in the AST tree
+type BreakStatement struct {
+	Token token.Token // the 'break' token
+}
+
+func (bs *BreakStatement) statementNode() {}
+
+// TokenLiteral returns token literal of the node
+func (bs *BreakStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (bs *BreakStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
+// String returns string representation of the node
+func (bs *BreakStatement) String() string {
+	return bs.TokenLiteral() + ";"
+}
+
+// ContinueStatement is type for continue statements in the AST tree
+type ContinueStatement struct {
+	Token token.Token // the 'continue' token
+}
+
+func (cs *ContinueStatement) statementNode() {}
+
+// TokenLiteral returns token literal of the node
+func (cs *ContinueStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (cs *ContinueStatement) Pos() token.Position {
+	return cs.Token.Pos
+}
+
+// String returns string representation of the node
+func (cs *ContinueStatement) String() string {
+	return cs.TokenLiteral() + ";"
+}
+
 // ExpressionStatement is type for expression statements in the AST tree
 type ExpressionStatement struct {
 	Token      token.Token // the first token of the expression
@@ -119,6 +252,11 @@ func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (es *ExpressionStatement) Pos() token.Position {
+	return es.Token.Pos
+}
+
 // String returns string representation of the node
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
@@ -141,6 +279,11 @@ func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (i *Identifier) Pos() token.Position {
+	return i.Token.Pos
+}
+
 // String returns string representation of the node
 func (i *Identifier) String() string {
 	return i.Value
@@ -159,11 +302,62 @@ func (il *IntegerLiteral) TokenLiteral() string {
 	return il.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (il *IntegerLiteral) Pos() token.Position {
+	return il.Token.Pos
+}
+
 // String returns string representation of the node
 func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
 
+// BigIntegerLiteral is type for integer expressions which don't fit into int64 in the AST tree
+type BigIntegerLiteral struct {
+	Token token.Token
+	Value *big.Int
+}
+
+func (bl *BigIntegerLiteral) expressionNode() {}
+
+// TokenLiteral returns token literal of the node
+func (bl *BigIntegerLiteral) TokenLiteral() string {
+	return bl.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (bl *BigIntegerLiteral) Pos() token.Position {
+	return bl.Token.Pos
+}
+
+// String returns string representation of the node
+func (bl *BigIntegerLiteral) String() string {
+	return bl.Token.Literal
+}
+
+// FloatLiteral is type for float expressions in the AST tree
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+
+// TokenLiteral returns token literal of the node
+func (fl *FloatLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (fl *FloatLiteral) Pos() token.Position {
+	return fl.Token.Pos
+}
+
+// String returns string representation of the node
+func (fl *FloatLiteral) String() string {
+	return fl.Token.Literal
+}
+
 // PrefixExpression is type for prefix expressions in the AST tree
 type PrefixExpression struct {
 	Token    token.Token
@@ -178,6 +372,11 @@ func (pe *PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (pe *PrefixExpression) Pos() token.Position {
+	return pe.Token.Pos
+}
+
 // String returns string representation of the node
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
@@ -205,6 +404,11 @@ func (ie *InfixExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (ie *InfixExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
 // String returns string representation of the node
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
@@ -218,6 +422,39 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// AssignExpression is type for assignments to variables and indexes in the AST tree
+type AssignExpression struct {
+	Token    token.Token // The assignment token, e.g. = or +=
+	Target   Expression  // Identifier or IndexExpression
+	Operator string      // operator of compound assignment, e.g. + for +=, empty for =
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+
+// TokenLiteral returns token literal of the node
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (ae *AssignExpression) Pos() token.Position {
+	return ae.Token.Pos
+}
+
+// String returns string representation of the node
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + "= ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 // Boolean is type for boolean expressions in the AST tree
 type Boolean struct {
 	Token token.Token
@@ -231,6 +468,11 @@ func (b *Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (b *Boolean) Pos() token.Position {
+	return b.Token.Pos
+}
+
 // String returns string representation of the node
 func (b *Boolean) String() string {
 	return b.Token.Literal
@@ -249,6 +491,11 @@ func (sl *StringLiteral) TokenLiteral() string {
 	return sl.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (sl *StringLiteral) Pos() token.Position {
+	return sl.Token.Pos
+}
+
 // String returns string representation of the node
 func (sl *StringLiteral) String() string {
 	return sl.Token.Literal
@@ -269,6 +516,11 @@ func (ie *IfExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (ie *IfExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
 // String returns string representation of the node
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
@@ -286,6 +538,115 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// WhileExpression is type for while loops in the AST tree
+type WhileExpression struct {
+	Token     token.Token // The 'while' token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (we *WhileExpression) expressionNode() {}
+
+// TokenLiteral returns token literal of the node
+func (we *WhileExpression) TokenLiteral() string {
+	return we.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (we *WhileExpression) Pos() token.Position {
+	return we.Token.Pos
+}
+
+// String returns string representation of the node
+func (we *WhileExpression) String() string {
+	return "while" + we.Condition.String() + " " + we.Body.String()
+}
+
+// ForExpression is type for for-in loops in the AST tree.
+// Value is bound to elements of arrays, characters of strings, numbers of ranges and keys of hashes
+// if Key is nil, otherwise Key is bound to indexes or keys and Value is bound to elements or values
+type ForExpression struct {
+	Token    token.Token // The 'for' token
+	Key      *Identifier // may be nil
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fe *ForExpression) expressionNode() {}
+
+// TokenLiteral returns token literal of the node
+func (fe *ForExpression) TokenLiteral() string {
+	return fe.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (fe *ForExpression) Pos() token.Position {
+	return fe.Token.Pos
+}
+
+// String returns string representation of the node
+func (fe *ForExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for(")
+	if fe.Key != nil {
+		out.WriteString(fe.Key.String())
+		out.WriteString(", ")
+	}
+	out.WriteString(fe.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(fe.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fe.Body.String())
+
+	return out.String()
+}
+
+// TryExpression is type for try expressions in the AST tree,
+// at least one of Catch and Finally blocks is not nil
+type TryExpression struct {
+	Token      token.Token // The 'try' token
+	Block      *BlockStatement
+	CatchParam *Identifier // identifier which is bound to caught error
+	Catch      *BlockStatement
+	Finally    *BlockStatement
+}
+
+func (te *TryExpression) expressionNode() {}
+
+// TokenLiteral returns token literal of the node
+func (te *TryExpression) TokenLiteral() string {
+	return te.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (te *TryExpression) Pos() token.Position {
+	return te.Token.Pos
+}
+
+// String returns string representation of the node
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+
+	if te.Catch != nil {
+		out.WriteString("catch(")
+		out.WriteString(te.CatchParam.String())
+		out.WriteString(") ")
+		out.WriteString(te.Catch.String())
+	}
+
+	if te.Finally != nil {
+		out.WriteString("finally ")
+		out.WriteString(te.Finally.String())
+	}
+
+	return out.String()
+}
+
 // BlockStatement is type for block statements in the AST tree
 type BlockStatement struct {
 	Token      token.Token // The '{' token
@@ -299,6 +660,11 @@ func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (bs *BlockStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
 // String returns string representation of the node
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
@@ -313,6 +679,7 @@ type FunctionLiteral struct {
 	Token      token.Token // The 'fn' token
 	Parameters []*Identifier
 	Body       *BlockStatement
+	Name       string // name of the binding if function is defined by let statement
 }
 
 func (fl *FunctionLiteral) expressionNode() {}
@@ -322,6 +689,11 @@ func (fl *FunctionLiteral) TokenLiteral() string {
 	return fl.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (fl *FunctionLiteral) Pos() token.Position {
+	return fl.Token.Pos
+}
+
 // String returns string representation of the node
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
@@ -354,6 +726,11 @@ func (ce *CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (ce *CallExpression) Pos() token.Position {
+	return ce.Token.Pos
+}
+
 // String returns string representation of the node
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
@@ -384,6 +761,11 @@ func (al *ArrayLiteral) TokenLiteral() string {
 	return al.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (al *ArrayLiteral) Pos() token.Position {
+	return al.Token.Pos
+}
+
 // String returns string representation of the node
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
@@ -400,9 +782,32 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// ImportExpression is type for import expressions in the AST tree
+type ImportExpression struct {
+	Token token.Token // the 'import' token
+	Path  string
+}
+
+func (ie *ImportExpression) expressionNode() {}
+
+// TokenLiteral returns token literal of the node
+func (ie *ImportExpression) TokenLiteral() string {
+	return ie.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (ie *ImportExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
+// String returns string representation of the node
+func (ie *ImportExpression) String() string {
+	return ie.TokenLiteral() + " \"" + ie.Path + "\""
+}
+
 // IndexExpression is type for `<expression>[<expression>]` index expressions
 type IndexExpression struct {
-	Token token.Token // The '[' token
+	Token token.Token // The '[' token or the '.' token of `<expression>.<name>` which is indexed by name
 	Left  Expression
 	Index Expression
 }
@@ -414,6 +819,11 @@ func (ie *IndexExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (ie *IndexExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
 // String returns string representation of the node
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
@@ -427,10 +837,16 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
-// HashLiteral is type for hash map literals
+// HashLiteral is type for hash map literals, pairs are kept in source order
 type HashLiteral struct {
 	Token token.Token
-	Pairs map[Expression]Expression
+	Pairs []HashLiteralPair
+}
+
+// HashLiteralPair is type for key and value expressions of pair in hash map literal
+type HashLiteralPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (hl *HashLiteral) expressionNode() {}
@@ -440,13 +856,18 @@ func (hl *HashLiteral) TokenLiteral() string {
 	return hl.Token.Literal
 }
 
+// Pos returns position of the node in the source code
+func (hl *HashLiteral) Pos() token.Position {
+	return hl.Token.Pos
+}
+
 // String returns string representation of the node
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for k, v := range hl.Pairs {
-		pairs = append(pairs, k.String()+":"+v.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 
 	out.WriteString("{")
@@ -455,3 +876,47 @@ func (hl *HashLiteral) String() string {
 
 	return out.String()
 }
+
+// BadStatement is type for placeholder of statement which failed to parse
+type BadStatement struct {
+	Token token.Token // the first token of the statement
+}
+
+func (bs *BadStatement) statementNode() {}
+
+// TokenLiteral returns token literal of the node
+func (bs *BadStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (bs *BadStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
+// String returns string representation of the node
+func (bs *BadStatement) String() string {
+	return "<bad statement>"
+}
+
+// BadExpression is type for placeholder of expression which failed to parse
+type BadExpression struct {
+	Token token.Token // the token where parsing failed
+}
+
+func (be *BadExpression) expressionNode() {}
+
+// TokenLiteral returns token literal of the node
+func (be *BadExpression) TokenLiteral() string {
+	return be.Token.Literal
+}
+
+// Pos returns position of the node in the source code
+func (be *BadExpression) Pos() token.Position {
+	return be.Token.Pos
+}
+
+// String returns string representation of the node
+func (be *BadExpression) String() string {
+	return "<bad expression>"
+}
@@ -0,0 +1,920 @@
+package compiler
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ythosa/pukiclang/src/ast"
+	"github.com/ythosa/pukiclang/src/code"
+	"github.com/ythosa/pukiclang/src/evaluator"
+	"github.com/ythosa/pukiclang/src/object"
+	"github.com/ythosa/pukiclang/src/token"
+)
+
+// placeholder is operand of jump instructions which is patched after the jump target is known
+const placeholder = 9999
+
+var infixOperators = map[string]code.Opcode{
+	"+":  code.OpAdd,
+	"-":  code.OpSub,
+	"*":  code.OpMul,
+	"/":  code.OpDiv,
+	"%":  code.OpMod,
+	"**": code.OpPow,
+	"==": code.OpEqual,
+	"!=": code.OpNotEqual,
+	">":  code.OpGreaterThan,
+	"<":  code.OpLessThan,
+	">=": code.OpGreaterEqual,
+	"<=": code.OpLessEqual,
+}
+
+var prefixOperators = map[string]code.Opcode{
+	"!": code.OpBang,
+	"-": code.OpMinus,
+}
+
+// Bytecode is type for result of compilation which is executed by the vm
+type Bytecode struct {
+	Instructions code.Instructions
+	Positions    map[int]token.Position
+	Constants    []object.Object
+	GlobalNames  []string
+}
+
+// EmittedInstruction is type for information about emitted instruction
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+// CompilationScope is type for instructions of function which is being compiled
+type CompilationScope struct {
+	instructions        code.Instructions
+	positions           map[int]token.Position
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+	tries               []tryBlock  // try expressions of the function which are being compiled
+	loops               []loopBlock // loops of the function which are being compiled
+}
+
+// loopBlock is type for loop which is being compiled
+type loopBlock struct {
+	tries  int   // number of try expressions which are outside of the loop
+	start  int   // address of instruction which starts the next iteration
+	breaks []int // addresses of jumps which are patched to the end of the loop
+}
+
+// tryBlock is type for try expression which is being compiled
+type tryBlock struct {
+	handlers int                 // number of error handlers which are set up by the try at the moment
+	finally  *ast.BlockStatement // block which must be executed before leaving the try, may be nil
+}
+
+// Compiler is type for compiler which turns AST tree into bytecode
+type Compiler struct {
+	constants   []object.Object
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+
+	pos token.Position // position of node which is being compiled
+
+	modules   map[string]*compiledModule // modules by paths of their files
+	importing []string                   // paths of modules which are being compiled
+
+	constantIndexes map[interface{}]int // indexes of constants which are shared by equal literals
+	err             error               // the first error of emitted instruction which operands do not fit
+
+	builtIns *object.Registry // registry of built in functions, standard functions are used if it is nil
+}
+
+// compiledModule is type for module which is compiled into function that is called on the first import
+type compiledModule struct {
+	global   int // index of global where the module is stored after the first import
+	function int // index of constant with function of the module
+}
+
+// New returns new compiler
+func New() *Compiler {
+	return NewWithState(NewSymbolTable(), []object.Object{})
+}
+
+// NewWithState returns new compiler which continues with passed global symbols and constants
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	return &Compiler{
+		constants:   constants,
+		symbolTable: s,
+		scopes: []CompilationScope{
+			{positions: make(map[int]token.Position)},
+		},
+		modules:         make(map[string]*compiledModule),
+		constantIndexes: make(map[interface{}]int),
+	}
+}
+
+// SetBuiltIns sets registry which built in functions of compiled programs are resolved from
+func (c *Compiler) SetBuiltIns(registry *object.Registry) {
+	c.builtIns = registry
+}
+
+// lookupBuiltIn returns built in function or namespace of built in functions by its name
+func (c *Compiler) lookupBuiltIn(name string) (object.Object, bool) {
+	if c.builtIns != nil {
+		return c.builtIns.Lookup(name)
+	}
+
+	return evaluator.LookupBuiltIn(name)
+}
+
+// Bytecode returns result of compilation
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Positions:    c.scopes[c.scopeIndex].positions,
+		Constants:    c.constants,
+		GlobalNames:  c.symbolTable.Global().Names(),
+	}
+}
+
+// Compile compiles node of AST tree, it returns error if program is too large for operands of instructions
+func (c *Compiler) Compile(node ast.Node) error {
+	if err := c.compile(node); err != nil {
+		return err
+	}
+
+	return c.err
+}
+
+func (c *Compiler) compile(node ast.Node) error {
+	prevPos := c.pos
+	c.pos = node.Pos()
+	defer func() { c.pos = prevPos }()
+
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+
+	case *ast.ExpressionStatement:
+		if err := c.Compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+
+	case *ast.LetStatement:
+		return c.compileLetStatement(node)
+
+	case *ast.ExportStatement:
+		return c.compileLetStatement(node.Statement)
+
+	case *ast.ReturnStatement:
+		if err := c.Compile(node.ReturnValue); err != nil {
+			return err
+		}
+		if err := c.compileLeaveTries(0); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+
+	case *ast.BreakStatement:
+		if err := c.compileLeaveTries(c.currentLoop().tries); err != nil {
+			return err
+		}
+
+		pos := c.emit(code.OpJump, placeholder)
+		loop := c.currentLoop()
+		loop.breaks = append(loop.breaks, pos)
+
+	case *ast.ContinueStatement:
+		if err := c.compileLeaveTries(c.currentLoop().tries); err != nil {
+			return err
+		}
+
+		c.emit(code.OpJump, c.currentLoop().start)
+
+	case *ast.AssignExpression:
+		return c.compileAssignExpression(node)
+
+	case *ast.WhileExpression:
+		return c.compileWhileExpression(node)
+
+	case *ast.ForExpression:
+		return c.compileForExpression(node)
+
+	case *ast.ThrowStatement:
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(code.OpThrow)
+
+	case *ast.TryExpression:
+		return c.compileTryExpression(node)
+
+	case *ast.ImportExpression:
+		return c.compileImportExpression(node)
+
+	case *ast.PrefixExpression:
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		op, ok := prefixOperators[node.Operator]
+		if !ok {
+			return fmt.Errorf("%s: unknown operator %s", node.Pos(), node.Operator)
+		}
+		c.emit(op)
+
+	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.compileLogicalExpression(node)
+		}
+
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		op, ok := infixOperators[node.Operator]
+		if !ok {
+			return fmt.Errorf("%s: unknown operator %s", node.Pos(), node.Operator)
+		}
+		c.emit(op)
+
+	case *ast.IntegerLiteral:
+		c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: node.Value}))
+
+	case *ast.BigIntegerLiteral:
+		c.emit(code.OpConstant, c.addConstant(&object.BigInt{Value: node.Value}))
+
+	case *ast.FloatLiteral:
+		c.emit(code.OpConstant, c.addConstant(&object.Float{Value: node.Value}))
+
+	case *ast.StringLiteral:
+		c.emit(code.OpConstant, c.addConstant(&object.String{Value: node.Value}))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+
+	case *ast.Identifier:
+		c.compileIdentifier(node)
+
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral(node)
+
+	case *ast.CallExpression:
+		if err := c.Compile(node.Function); err != nil {
+			return err
+		}
+
+		for _, a := range node.Arguments {
+			if err := c.Compile(a); err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpCall, len(node.Arguments))
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			if err := c.Compile(el); err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		return c.compileHashLiteral(node)
+
+	case *ast.IndexExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Index); err != nil {
+			return err
+		}
+
+		c.emit(code.OpIndex)
+
+	default:
+		return fmt.Errorf("%s: unsupported node %T", node.Pos(), node)
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileLetStatement(node *ast.LetStatement) error {
+	var symbol Symbol
+
+	// function is defined before compilation of its body, so it is able to call itself
+	if _, ok := node.Value.(*ast.FunctionLiteral); ok {
+		symbol = c.symbolTable.Define(node.Name.Value)
+	}
+
+	if err := c.Compile(node.Value); err != nil {
+		return err
+	}
+
+	if symbol.Name == "" {
+		symbol = c.symbolTable.Define(node.Name.Value)
+	}
+
+	c.storeSymbol(symbol)
+
+	return nil
+}
+
+// storeSymbol emits instruction which pops value from the stack into defined symbol
+func (c *Compiler) storeSymbol(s Symbol) {
+	if s.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, s.Index)
+	} else {
+		c.emit(code.OpSetLocal, s.Index)
+	}
+}
+
+// compileAssignExpression compiles assignment, so assigned value is left on the stack
+func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		if node.Operator != "" {
+			if err := c.Compile(target); err != nil {
+				return err
+			}
+		}
+
+		if err := c.compileAssignedValue(node); err != nil {
+			return err
+		}
+
+		symbol, ok := c.symbolTable.Resolve(target.Value)
+		if !ok {
+			// unknown variable may be defined later, otherwise the vm reports it
+			symbol = c.symbolTable.Global().Define(target.Value)
+		}
+
+		switch symbol.Scope {
+		case GlobalScope:
+			c.emit(code.OpAssignGlobal, symbol.Index)
+		case LocalScope:
+			c.emit(code.OpAssignLocal, symbol.Index)
+		case FreeScope:
+			c.emit(code.OpAssignFree, symbol.Index)
+		}
+
+	case *ast.IndexExpression:
+		if err := c.Compile(target.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(target.Index); err != nil {
+			return err
+		}
+
+		if node.Operator != "" {
+			c.emit(code.OpDup2)
+
+			c.pos = target.Pos()
+			c.emit(code.OpIndex)
+			c.pos = node.Pos()
+		}
+
+		if err := c.compileAssignedValue(node); err != nil {
+			return err
+		}
+
+		c.emit(code.OpSetIndex)
+
+	default:
+		return fmt.Errorf("%s: cannot assign to %s", node.Pos(), node.Target.String())
+	}
+
+	return nil
+}
+
+// compileAssignedValue compiles value of assignment, compound assignment combines it with
+// current value which is already on the stack
+func (c *Compiler) compileAssignedValue(node *ast.AssignExpression) error {
+	if err := c.Compile(node.Value); err != nil {
+		return err
+	}
+
+	if node.Operator == "" {
+		return nil
+	}
+
+	op, ok := infixOperators[node.Operator]
+	if !ok {
+		return fmt.Errorf("%s: unknown operator %s", node.Pos(), node.Operator)
+	}
+	c.emit(op)
+
+	return nil
+}
+
+// compileTryExpression compiles try expression, so value of try block or catch block is left on the stack.
+// Errors are handled by handlers which are set up by the vm, the finally block is compiled
+// for the normal exit and for the handler which rethrows error after the block
+func (c *Compiler) compileTryExpression(node *ast.TryExpression) error {
+	scope := &c.scopes[c.scopeIndex]
+	scope.tries = append(scope.tries, tryBlock{finally: node.Finally})
+
+	finallyPos := -1
+	if node.Finally != nil {
+		finallyPos = c.emit(code.OpSetupFinally, placeholder)
+		c.currentTry().handlers++
+	}
+
+	catchPos := -1
+	if node.Catch != nil {
+		catchPos = c.emit(code.OpSetupCatch, placeholder)
+		c.currentTry().handlers++
+	}
+
+	if err := c.compileBlockValue(node.Block); err != nil {
+		return err
+	}
+
+	if node.Catch != nil {
+		c.emit(code.OpPopHandler)
+		c.currentTry().handlers--
+
+		jumpPos := c.emit(code.OpJump, placeholder)
+
+		// the vm pushes caught error before jump to the catch block
+		c.changeOperand(catchPos, len(c.currentInstructions()))
+
+		// catch block has its own scope, so catch parameter does not override variable of the enclosing scope
+		c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+		c.storeSymbol(c.symbolTable.Define(node.CatchParam.Value))
+
+		err := c.compileBlockValue(node.Catch)
+		c.symbolTable = c.symbolTable.Outer
+		if err != nil {
+			return err
+		}
+
+		c.changeOperand(jumpPos, len(c.currentInstructions()))
+	}
+
+	if node.Finally != nil {
+		c.emit(code.OpPopHandler)
+	}
+
+	scope = &c.scopes[c.scopeIndex]
+	scope.tries = scope.tries[:len(scope.tries)-1]
+
+	if node.Finally == nil {
+		return nil
+	}
+
+	if err := c.Compile(node.Finally); err != nil {
+		return err
+	}
+
+	jumpPos := c.emit(code.OpJump, placeholder)
+
+	// the vm pushes error which is rethrown after the finally block
+	c.changeOperand(finallyPos, len(c.currentInstructions()))
+
+	if err := c.Compile(node.Finally); err != nil {
+		return err
+	}
+	c.emit(code.OpThrow)
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	return nil
+}
+
+func (c *Compiler) compileWhileExpression(node *ast.WhileExpression) error {
+	start := len(c.currentInstructions())
+
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+
+	exitPos := c.emit(code.OpJumpNotTruthy, placeholder)
+
+	if err := c.compileLoopBody(node.Body, start); err != nil {
+		return err
+	}
+
+	c.changeOperand(exitPos, len(c.currentInstructions()))
+	c.emit(code.OpNull)
+
+	return nil
+}
+
+// compileForExpression compiles for loop, iterator of iterable is kept in hidden variable
+func (c *Compiler) compileForExpression(node *ast.ForExpression) error {
+	if err := c.Compile(node.Iterable); err != nil {
+		return err
+	}
+
+	c.pos = node.Iterable.Pos()
+	c.emit(code.OpGetIter)
+	c.pos = node.Pos()
+
+	iterator := c.symbolTable.Define(fmt.Sprintf("$iterator%d", len(c.scopes[c.scopeIndex].loops)))
+	c.storeSymbol(iterator)
+
+	start := len(c.currentInstructions())
+	c.loadSymbol(iterator)
+
+	variables := 1
+	if node.Key != nil {
+		variables = 2
+	}
+	exitPos := c.emit(code.OpIterNext, placeholder, variables)
+
+	c.storeSymbol(c.symbolTable.Define(node.Value.Value))
+	if node.Key != nil {
+		c.storeSymbol(c.symbolTable.Define(node.Key.Value))
+	}
+
+	if err := c.compileLoopBody(node.Body, start); err != nil {
+		return err
+	}
+
+	c.changeOperand(exitPos, len(c.currentInstructions()), variables)
+	c.emit(code.OpNull)
+
+	return nil
+}
+
+// compileLoopBody compiles body of loop which jumps to start, breaks are patched to the end of the body
+func (c *Compiler) compileLoopBody(body *ast.BlockStatement, start int) error {
+	scope := &c.scopes[c.scopeIndex]
+	scope.loops = append(scope.loops, loopBlock{tries: len(scope.tries), start: start})
+
+	if err := c.Compile(body); err != nil {
+		return err
+	}
+
+	c.emit(code.OpJump, start)
+
+	loop := c.currentLoop()
+	for _, pos := range loop.breaks {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+
+	scope = &c.scopes[c.scopeIndex]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+
+	return nil
+}
+
+func (c *Compiler) currentLoop() *loopBlock {
+	loops := c.scopes[c.scopeIndex].loops
+	return &loops[len(loops)-1]
+}
+
+func (c *Compiler) currentTry() *tryBlock {
+	tries := c.scopes[c.scopeIndex].tries
+	return &tries[len(tries)-1]
+}
+
+// compileLeaveTries compiles finally blocks of try expressions which are left by return, break
+// or continue, from is the number of outer try expressions which are not left. Handlers of left
+// try expressions are removed before, so they do not catch errors of finally blocks
+func (c *Compiler) compileLeaveTries(from int) error {
+	tries := append([]tryBlock(nil), c.scopes[c.scopeIndex].tries...)
+	defer func() { c.scopes[c.scopeIndex].tries = tries }()
+
+	outermost := len(tries)
+	for i := from; i < len(tries); i++ {
+		if tries[i].finally != nil {
+			outermost = i
+			break
+		}
+	}
+
+	for i := len(tries) - 1; i >= outermost; i-- {
+		for h := 0; h < tries[i].handlers; h++ {
+			c.emit(code.OpPopHandler)
+		}
+
+		if tries[i].finally != nil {
+			// finally block is outside of its try expression
+			c.scopes[c.scopeIndex].tries = append([]tryBlock(nil), tries[:i]...)
+			if err := c.Compile(tries[i].finally); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// compileLogicalExpression compiles && and || so right operand is skipped when left one decides result
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	if err := c.Compile(node.Left); err != nil {
+		return err
+	}
+
+	jump := code.OpJumpNotTruthyOrPop
+	if node.Operator == "||" {
+		jump = code.OpJumpTruthyOrPop
+	}
+	jumpPos := c.emit(jump, placeholder)
+
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	return nil
+}
+
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, placeholder)
+
+	if err := c.compileBlockValue(node.Consequence); err != nil {
+		return err
+	}
+
+	jumpPos := c.emit(code.OpJump, placeholder)
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else if err := c.compileBlockValue(node.Alternative); err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	return nil
+}
+
+// compileBlockValue compiles block, so it leaves value of its last expression on the stack
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	if err := c.Compile(block); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileIdentifier(node *ast.Identifier) {
+	symbol, ok := c.symbolTable.Resolve(node.Value)
+	if !ok {
+		if builtIn, ok := c.lookupBuiltIn(node.Value); ok {
+			c.emit(code.OpConstant, c.addConstant(builtIn))
+			return
+		}
+
+		// unknown identifier may be defined later, otherwise the vm reports it
+		symbol = c.symbolTable.Global().Define(node.Value)
+	}
+
+	c.loadSymbol(symbol)
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	}
+}
+
+func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral) error {
+	c.enterScope()
+
+	for _, p := range node.Parameters {
+		c.symbolTable.Define(p.Value)
+	}
+
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+
+	freeSymbols := c.symbolTable.FreeSymbols
+	localNames := c.symbolTable.Names()
+	positions := c.scopes[c.scopeIndex].positions
+	instructions := c.leaveScope()
+
+	captures := make([]object.Capture, len(freeSymbols))
+	for i, s := range freeSymbols {
+		captures[i] = object.Capture{
+			Name:  s.Name,
+			Local: s.Scope == LocalScope,
+			Index: s.Index,
+		}
+	}
+
+	compiledFn := &object.CompiledFunction{
+		Instructions:  instructions,
+		Positions:     positions,
+		NumLocals:     len(localNames),
+		NumParameters: len(node.Parameters),
+		LocalNames:    localNames,
+		Captures:      captures,
+		Name:          node.Name,
+	}
+
+	c.emit(code.OpClosure, c.addConstant(compiledFn))
+
+	return nil
+}
+
+func (c *Compiler) compileHashLiteral(node *ast.HashLiteral) error {
+	for _, pair := range node.Pairs {
+		if err := c.Compile(pair.Key); err != nil {
+			return err
+		}
+		if err := c.Compile(pair.Value); err != nil {
+			return err
+		}
+	}
+
+	c.emit(code.OpHash, len(node.Pairs)*2)
+
+	return nil
+}
+
+// floatConstant and bigIntConstant are types for keys of shared float and big integer constants
+type (
+	floatConstant  uint64
+	bigIntConstant string
+)
+
+// addConstant adds constant and returns its index, equal literals and built in functions share one constant
+func (c *Compiler) addConstant(obj object.Object) int {
+	var key interface{}
+	switch obj := obj.(type) {
+	case *object.Integer:
+		key = *obj
+	case *object.String:
+		key = *obj
+	case *object.Float:
+		key = floatConstant(math.Float64bits(obj.Value))
+	case *object.BigInt:
+		key = bigIntConstant(obj.Value.String())
+	case *object.BuiltIn:
+		key = obj
+	}
+
+	if key != nil {
+		if index, ok := c.constantIndexes[key]; ok {
+			return index
+		}
+		c.constantIndexes[key] = len(c.constants)
+	}
+
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+// emit adds instruction and returns its position, if operands of instruction do not fit into their widths,
+// compilation error is recorded and returned by Compile
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	c.checkOperands(op, operands...)
+
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+
+	c.setLastInstruction(op, pos)
+
+	return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	scope := &c.scopes[c.scopeIndex]
+
+	posNewInstruction := len(scope.instructions)
+	scope.instructions = append(scope.instructions, ins...)
+
+	if c.pos.IsValid() {
+		scope.positions[posNewInstruction] = c.pos
+	}
+
+	return posNewInstruction
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	scope := &c.scopes[c.scopeIndex]
+
+	scope.previousInstruction = scope.lastInstruction
+	scope.lastInstruction = EmittedInstruction{Opcode: op, Position: pos}
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	scope := &c.scopes[c.scopeIndex]
+	last := scope.lastInstruction
+
+	scope.instructions = scope.instructions[:last.Position]
+	delete(scope.positions, last.Position)
+	scope.lastInstruction = scope.previousInstruction
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	scope := &c.scopes[c.scopeIndex]
+	lastPos := scope.lastInstruction.Position
+
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+	scope.lastInstruction.Opcode = code.OpReturnValue
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+func (c *Compiler) changeOperand(opPos int, operands ...int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	c.checkOperands(op, operands...)
+
+	newInstruction := code.Make(op, operands...)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+// checkOperands records error if operands of instruction do not fit into their widths
+func (c *Compiler) checkOperands(op code.Opcode, operands ...int) {
+	if c.err != nil {
+		return
+	}
+
+	if err := code.CheckOperands(op, operands...); err != nil {
+		c.err = fmt.Errorf("%s: program is too large: %s", c.pos, err)
+	}
+}
+
+func (c *Compiler) enterScope() {
+	c.scopes = append(c.scopes, CompilationScope{
+		positions: make(map[int]token.Position),
+	})
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
@@ -0,0 +1,523 @@
+package compiler_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ythosa/pukiclang/src/code"
+	"github.com/ythosa/pukiclang/src/compiler"
+	"github.com/ythosa/pukiclang/src/lexer"
+	"github.com/ythosa/pukiclang/src/object"
+	"github.com/ythosa/pukiclang/src/parser"
+)
+
+type compilerTestCase struct {
+	input                string
+	expectedConstants    []interface{}
+	expectedInstructions []code.Instructions
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1 + 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 < 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpLessThan),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "-1; !true",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpMinus),
+				code.Make(code.OpPop),
+				code.Make(code.OpTrue),
+				code.Make(code.OpBang),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "if (true) { 10 }; 3333;",
+			expectedConstants: []interface{}{10, 3333},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpJump, 11),
+				// 0010
+				code.Make(code.OpNull),
+				// 0011
+				code.Make(code.OpPop),
+				// 0012
+				code.Make(code.OpConstant, 1),
+				// 0015
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "if (true) { let a = 1; }",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 14),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpJump, 15),
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestLogicalExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "true && 1",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthyOrPop, 7),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "false || 1",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpFalse),
+				code.Make(code.OpJumpTruthyOrPop, 7),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestTryExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "try { 1 } catch (e) { 2 }; 3",
+			expectedConstants: []interface{}{1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpSetupCatch, 10),
+				// 0003
+				code.Make(code.OpConstant, 0),
+				// 0006
+				code.Make(code.OpPopHandler),
+				// 0007
+				code.Make(code.OpJump, 16),
+				// 0010
+				code.Make(code.OpSetGlobal, 0),
+				// 0013
+				code.Make(code.OpConstant, 1),
+				// 0016
+				code.Make(code.OpPop),
+				// 0017
+				code.Make(code.OpConstant, 2),
+				// 0020
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "try { 1 } finally { 2 }",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpSetupFinally, 14),
+				// 0003
+				code.Make(code.OpConstant, 0),
+				// 0006
+				code.Make(code.OpPopHandler),
+				// 0007
+				code.Make(code.OpConstant, 1),
+				// 0010
+				code.Make(code.OpPop),
+				// 0011
+				code.Make(code.OpJump, 19),
+				// 0014
+				code.Make(code.OpConstant, 1),
+				// 0017
+				code.Make(code.OpPop),
+				// 0018
+				code.Make(code.OpThrow),
+				// 0019
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "while (true) { break }",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpJump, 10),
+				// 0007
+				code.Make(code.OpJump, 0),
+				// 0010
+				code.Make(code.OpNull),
+				// 0011
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "for (x in [1]) { continue }",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpArray, 1),
+				// 0006
+				code.Make(code.OpGetIter),
+				// 0007
+				code.Make(code.OpSetGlobal, 0),
+				// 0010
+				code.Make(code.OpGetGlobal, 0),
+				// 0013
+				code.Make(code.OpIterNext, 26, 1),
+				// 0017
+				code.Make(code.OpSetGlobal, 1),
+				// 0020
+				code.Make(code.OpJump, 10),
+				// 0023
+				code.Make(code.OpJump, 10),
+				// 0026
+				code.Make(code.OpNull),
+				// 0027
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let one = 1; let one = 2; one;",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "undefined; let defined = 1;",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 1),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "fn(a) { fn(b) { a + b } }",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpClosure, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestCaptures(t *testing.T) {
+	input := `
+	fn(a) {
+		let c = 1;
+		fn(b) {
+			fn() { a + b + c }
+		}
+	}`
+
+	bytecode := compile(t, input)
+
+	innermost, ok := bytecode.Constants[1].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("constant is not CompiledFunction. got=%T", bytecode.Constants[1])
+	}
+
+	expected := []object.Capture{
+		{Name: "a", Local: false, Index: 0},
+		{Name: "b", Local: true, Index: 0},
+		{Name: "c", Local: false, Index: 1},
+	}
+
+	if len(innermost.Captures) != len(expected) {
+		t.Fatalf("wrong number of captures. want=%d, got=%d",
+			len(expected), len(innermost.Captures))
+	}
+
+	for i, capture := range expected {
+		if innermost.Captures[i] != capture {
+			t.Errorf("capture %d wrong. want=%+v, got=%+v", i, capture, innermost.Captures[i])
+		}
+	}
+}
+
+func TestInstructionPositions(t *testing.T) {
+	bytecode := compile(t, "1 +\n  true")
+
+	// OpConstant 0, OpTrue, OpAdd
+	pos, ok := bytecode.Positions[4]
+	if !ok {
+		t.Fatalf("no position for OpAdd")
+	}
+
+	if pos.String() != "1:3" {
+		t.Errorf("wrong position of OpAdd. got=%s", pos)
+	}
+}
+
+func TestConstantsAreShared(t *testing.T) {
+	runCompilerTests(t, []compilerTestCase{
+		{
+			input:             `1; "a"; 1.5; 1; "a"; 1.5; len; len`,
+			expectedConstants: []interface{}{1, "a", 1.5, "built in function len"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpPop),
+			},
+		},
+	})
+}
+
+func TestOperandLimits(t *testing.T) {
+	// numbered repeats format n times with distinct numbers or identifiers which consist of letters
+	numbered := func(format string, n int) string {
+		var out strings.Builder
+		for i := 0; i < n; i++ {
+			if strings.Contains(format, "%s") {
+				name := ""
+				for j := i; j > 0 || name == ""; j /= 26 {
+					name = string(rune('a'+j%26)) + name
+				}
+				fmt.Fprintf(&out, format, name)
+			} else {
+				fmt.Fprintf(&out, format, i)
+			}
+		}
+		return out.String()
+	}
+
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{
+			"let x = 0;\n" + strings.Repeat("x = x + 1;\n", 9000) + "if (x) { 1 }",
+			"9002:1: program is too large: operand 99018 of OpJumpNotTruthy is out of range [0, 65535]",
+		},
+		{
+			"let x = 0;\nif (true) {\n" + strings.Repeat("x = x + 1;\n", 9000) + "}",
+			"2:1: program is too large: operand 99012 of OpJumpNotTruthy is out of range [0, 65535]",
+		},
+		{
+			"fn() {\n" + numbered("let x%s = 1;\n", 300) + "}",
+			"258:1: program is too large: operand 256 of OpSetLocal is out of range [0, 255]",
+		},
+		{
+			numbered("%d;", 70000),
+			"1:382107: program is too large: operand 65536 of OpConstant is out of range [0, 65535]",
+		},
+		{
+			numbered("let x%s = 1;", 70000),
+			"1:899227: program is too large: operand 65536 of OpSetGlobal is out of range [0, 65535]",
+		},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		err := compiler.New().Compile(program)
+		if err == nil || err.Error() != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%v", tt.expectedError, err)
+		}
+	}
+}
+
+func compile(t *testing.T, input string) *compiler.Bytecode {
+	program := parser.New(lexer.New(input)).ParseProgram()
+
+	c := compiler.New()
+	if err := c.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	return c.Bytecode()
+}
+
+func runCompilerTests(t *testing.T, tests []compilerTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		bytecode := compile(t, tt.input)
+
+		if err := testInstructions(tt.expectedInstructions, bytecode.Instructions); err != nil {
+			t.Fatalf("testInstructions failed for %q: %s", tt.input, err)
+		}
+
+		if err := testConstants(tt.expectedConstants, bytecode.Constants); err != nil {
+			t.Fatalf("testConstants failed for %q: %s", tt.input, err)
+		}
+	}
+}
+
+func concatInstructions(s []code.Instructions) code.Instructions {
+	out := code.Instructions{}
+	for _, ins := range s {
+		out = append(out, ins...)
+	}
+
+	return out
+}
+
+func testInstructions(expected []code.Instructions, actual code.Instructions) error {
+	concatted := concatInstructions(expected)
+
+	if len(actual) != len(concatted) {
+		return fmt.Errorf("wrong instructions length.\nwant=%q\ngot =%q",
+			concatted, actual)
+	}
+
+	for i, ins := range concatted {
+		if actual[i] != ins {
+			return fmt.Errorf("wrong instruction at %d.\nwant=%q\ngot =%q",
+				i, concatted, actual)
+		}
+	}
+
+	return nil
+}
+
+func testConstants(expected []interface{}, actual []object.Object) error {
+	if len(expected) != len(actual) {
+		return fmt.Errorf("wrong number of constants. got=%d, want=%d",
+			len(actual), len(expected))
+	}
+
+	for i, constant := range expected {
+		switch constant := constant.(type) {
+		case int:
+			integer, ok := actual[i].(*object.Integer)
+			if !ok || integer.Value != int64(constant) {
+				return fmt.Errorf("constant %d - wrong integer. want=%d, got=%s",
+					i, constant, actual[i].Inspect())
+			}
+
+		case float64:
+			float, ok := actual[i].(*object.Float)
+			if !ok || float.Value != constant {
+				return fmt.Errorf("constant %d - wrong float. want=%g, got=%s",
+					i, constant, actual[i].Inspect())
+			}
+
+		case string:
+			if actual[i].Inspect() != constant {
+				return fmt.Errorf("constant %d - wrong object. want=%q, got=%q",
+					i, constant, actual[i].Inspect())
+			}
+
+		case []code.Instructions:
+			fn, ok := actual[i].(*object.CompiledFunction)
+			if !ok {
+				return fmt.Errorf("constant %d - not a function: %T", i, actual[i])
+			}
+
+			if err := testInstructions(constant, fn.Instructions); err != nil {
+				return fmt.Errorf("constant %d - testInstructions failed: %s", i, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/ythosa/pukiclang/src/ast"
+	"github.com/ythosa/pukiclang/src/code"
+	"github.com/ythosa/pukiclang/src/evaluator"
+	"github.com/ythosa/pukiclang/src/loader"
+	"github.com/ythosa/pukiclang/src/object"
+	"github.com/ythosa/pukiclang/src/token"
+)
+
+// compileImportExpression compiles imported module once, module files are resolved and compiled
+// at compilation time, but missing modules, their syntax errors and import cycles raise errors
+// when the import is executed, like in the evaluator
+func (c *Compiler) compileImportExpression(node *ast.ImportExpression) error {
+	path, err := loader.Resolve(node.Token.Pos.Filename, node.Path)
+	if err != nil {
+		c.emitImportError(err)
+		return nil
+	}
+
+	module, ok := c.modules[path]
+	if !ok {
+		for _, importing := range c.importing {
+			if importing == path {
+				c.emitImportError(fmt.Errorf("import cycle: %s", node.Path))
+				return nil
+			}
+		}
+
+		program, err := loader.Load(path)
+		if err != nil {
+			c.emitImportError(err)
+			return nil
+		}
+
+		if module, err = c.compileModule(path, node.Path, program); err != nil {
+			return err
+		}
+	}
+
+	c.emit(code.OpLoadModule, module.global, module.function)
+
+	return nil
+}
+
+// emitImportError emits instruction which raises error of module which can not be imported
+func (c *Compiler) emitImportError(err error) {
+	c.emit(code.OpImportError, c.addConstant(&object.String{Value: err.Error()}))
+}
+
+// compileModule compiles program of module into function which stores module in global and returns it
+func (c *Compiler) compileModule(path string, name string, program *ast.Program) (*compiledModule, error) {
+	c.importing = append(c.importing, path)
+	importerSymbols := c.symbolTable
+	c.symbolTable = NewModuleSymbolTable(importerSymbols)
+	c.scopes = append(c.scopes, CompilationScope{positions: make(map[int]token.Position)})
+	c.scopeIndex++
+
+	defer func() {
+		c.importing = c.importing[:len(c.importing)-1]
+		c.symbolTable = importerSymbols
+		c.scopes = c.scopes[:len(c.scopes)-1]
+		c.scopeIndex--
+	}()
+
+	if err := c.Compile(program); err != nil {
+		return nil, err
+	}
+
+	// module is built from names of exported bindings and indexes of their globals,
+	// so the vm reads current values of the bindings
+	exports := loader.Exports(program)
+	for _, export := range exports {
+		symbol, _ := c.symbolTable.Resolve(export)
+		c.emit(code.OpConstant, c.addConstant(&object.String{Value: export}))
+		c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: int64(symbol.Index)}))
+	}
+
+	global := c.symbolTable.DefineHidden(evaluator.ModuleFunctionName(name))
+	c.emit(code.OpModule, c.addConstant(&object.String{Value: name}), len(exports)*2)
+	c.emit(code.OpSetGlobal, global.Index)
+	c.emit(code.OpGetGlobal, global.Index)
+	c.emit(code.OpReturnValue)
+
+	fn := &object.CompiledFunction{
+		Instructions: c.currentInstructions(),
+		Positions:    c.scopes[c.scopeIndex].positions,
+		Name:         evaluator.ModuleFunctionName(name),
+	}
+
+	module := &compiledModule{global: global.Index, function: c.addConstant(fn)}
+	c.modules[path] = module
+
+	return module, nil
+}
@@ -0,0 +1,160 @@
+package compiler
+
+// SymbolScope is type for scope of symbol
+type SymbolScope string
+
+// Scopes of symbols
+const (
+	GlobalScope SymbolScope = "GLOBAL"
+	LocalScope  SymbolScope = "LOCAL"
+	FreeScope   SymbolScope = "FREE"
+)
+
+// Symbol is type for information about identifier
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+// SymbolTable is type for table of symbols which are defined in one scope
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	FreeSymbols []Symbol // symbols of outer scopes captured by the current one
+
+	store map[string]Symbol
+	names []string // names of defined symbols by their indexes
+
+	program *SymbolTable // global table of the program which allocates indexes of globals of module table
+	block   bool         // symbols of block table are visible only in the block, but they are allocated by outer table
+}
+
+// NewSymbolTable returns new global symbol table
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{
+		store: make(map[string]Symbol),
+	}
+}
+
+// NewEnclosedSymbolTable returns new symbol table with pointer on outer table
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+
+	return s
+}
+
+// NewBlockSymbolTable returns new symbol table of block, e.g. catch block, its symbols are not visible
+// outside of the block, but they are stored in locals or globals of the enclosing table
+func NewBlockSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewEnclosedSymbolTable(outer)
+	s.block = true
+
+	return s
+}
+
+// NewModuleSymbolTable returns new global symbol table of module, its symbols are not visible to the program
+// and other modules, but indexes of them are allocated in the global table of the program,
+// so all modules share globals of the vm
+func NewModuleSymbolTable(importer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.program = importer.programTable()
+
+	return s
+}
+
+// Define defines symbol in the table, redefinition of symbol returns already defined one
+func (s *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := s.store[name]; ok && symbol.Scope != FreeScope {
+		return symbol
+	}
+
+	symbol := Symbol{Name: name}
+	symbol.Scope, symbol.Index = s.allocateSymbol(name)
+	s.store[name] = symbol
+
+	return symbol
+}
+
+// allocateSymbol returns scope and index of new symbol defined in the table
+func (s *SymbolTable) allocateSymbol(name string) (SymbolScope, int) {
+	switch {
+	case s.block:
+		return s.Outer.allocateSymbol(name)
+	case s.Outer != nil:
+		return LocalScope, s.allocate(name)
+	case s.program != nil:
+		return GlobalScope, s.program.allocate(name)
+	default:
+		return GlobalScope, s.allocate(name)
+	}
+}
+
+// DefineHidden allocates global of the program which is not visible by name, name is used only in error messages
+func (s *SymbolTable) DefineHidden(name string) Symbol {
+	return Symbol{
+		Name:  name,
+		Scope: GlobalScope,
+		Index: s.programTable().allocate(name),
+	}
+}
+
+// allocate returns index of the next symbol of the table
+func (s *SymbolTable) allocate(name string) int {
+	s.names = append(s.names, name)
+	return len(s.names) - 1
+}
+
+// programTable returns global table of the program
+func (s *SymbolTable) programTable() *SymbolTable {
+	global := s.Global()
+	if global.program != nil {
+		return global.program
+	}
+
+	return global
+}
+
+// Resolve returns symbol with passed name and is symbol defined in the table or in outer tables
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	symbol, ok := s.store[name]
+	if ok || s.Outer == nil {
+		return symbol, ok
+	}
+
+	// block is executed in the frame of outer table, so its symbols are not free in the block
+	symbol, ok = s.Outer.Resolve(name)
+	if !ok || symbol.Scope == GlobalScope || s.block {
+		return symbol, ok
+	}
+
+	return s.defineFree(symbol), true
+}
+
+// Global returns the outermost table of the current one
+func (s *SymbolTable) Global() *SymbolTable {
+	if s.Outer == nil {
+		return s
+	}
+
+	return s.Outer.Global()
+}
+
+// Names returns names of symbols defined in the table by their indexes
+func (s *SymbolTable) Names() []string {
+	return s.names
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{
+		Name:  original.Name,
+		Scope: FreeScope,
+		Index: len(s.FreeSymbols) - 1,
+	}
+	s.store[original.Name] = symbol
+
+	return symbol
+}
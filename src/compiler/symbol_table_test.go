@@ -0,0 +1,140 @@
+package compiler_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ythosa/pukiclang/src/compiler"
+)
+
+func TestResolveFree(t *testing.T) {
+	global := compiler.NewSymbolTable()
+	global.Define("a")
+
+	first := compiler.NewEnclosedSymbolTable(global)
+	first.Define("b")
+
+	second := compiler.NewEnclosedSymbolTable(first)
+	second.Define("c")
+
+	tests := []struct {
+		name     string
+		expected compiler.Symbol
+	}{
+		{"a", compiler.Symbol{Name: "a", Scope: compiler.GlobalScope, Index: 0}},
+		{"b", compiler.Symbol{Name: "b", Scope: compiler.FreeScope, Index: 0}},
+		{"c", compiler.Symbol{Name: "c", Scope: compiler.LocalScope, Index: 0}},
+	}
+
+	for _, tt := range tests {
+		result, ok := second.Resolve(tt.name)
+		if !ok {
+			t.Errorf("name %s not resolvable", tt.name)
+			continue
+		}
+
+		if result != tt.expected {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", tt.name, tt.expected, result)
+		}
+	}
+
+	if len(second.FreeSymbols) != 1 || second.FreeSymbols[0].Name != "b" {
+		t.Errorf("wrong free symbols. got=%+v", second.FreeSymbols)
+	}
+
+	if _, ok := second.Resolve("d"); ok {
+		t.Errorf("name d resolved, but was not defined")
+	}
+}
+
+func TestModuleSymbolTable(t *testing.T) {
+	program := compiler.NewSymbolTable()
+	program.Define("a")
+
+	module := compiler.NewModuleSymbolTable(program)
+	moduleA := module.Define("a")
+	moduleB := module.Define("b")
+
+	nested := compiler.NewModuleSymbolTable(compiler.NewEnclosedSymbolTable(module))
+	nestedA := nested.Define("a")
+
+	hidden := module.DefineHidden("module m")
+	programB := program.Define("b")
+
+	symbols := []compiler.Symbol{moduleA, moduleB, nestedA, hidden, programB}
+	for i, symbol := range symbols {
+		if symbol.Scope != compiler.GlobalScope || symbol.Index != i+1 {
+			t.Errorf("wrong symbol %d. got=%+v", i, symbol)
+		}
+	}
+
+	if _, ok := module.Resolve("c"); ok {
+		t.Errorf("symbol of module is resolved, but was not defined")
+	}
+	if symbol, _ := program.Resolve("b"); symbol != programB {
+		t.Errorf("symbol of module is visible in program. got=%+v", symbol)
+	}
+
+	expectedNames := []string{"a", "a", "b", "a", "module m", "b"}
+	if strings.Join(program.Names(), " ") != strings.Join(expectedNames, " ") {
+		t.Errorf("wrong names of globals. got=%v", program.Names())
+	}
+}
+
+func TestBlockSymbolTable(t *testing.T) {
+	global := compiler.NewSymbolTable()
+	globalE := global.Define("e")
+	globalBlock := compiler.NewBlockSymbolTable(global)
+	blockE := globalBlock.Define("e")
+
+	local := compiler.NewEnclosedSymbolTable(global)
+	localA := local.Define("a")
+	localBlock := compiler.NewBlockSymbolTable(local)
+	localE := localBlock.Define("e")
+
+	inner := compiler.NewEnclosedSymbolTable(localBlock)
+
+	tests := []struct {
+		table    *compiler.SymbolTable
+		name     string
+		expected compiler.Symbol
+	}{
+		{global, "e", globalE},
+		{globalBlock, "e", compiler.Symbol{Name: "e", Scope: compiler.GlobalScope, Index: 1}},
+		{local, "e", globalE},
+		{localBlock, "a", localA},
+		{localBlock, "e", compiler.Symbol{Name: "e", Scope: compiler.LocalScope, Index: 1}},
+		{inner, "e", compiler.Symbol{Name: "e", Scope: compiler.FreeScope, Index: 0}},
+	}
+
+	for _, tt := range tests {
+		if result, ok := tt.table.Resolve(tt.name); !ok || result != tt.expected {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", tt.name, tt.expected, result)
+		}
+	}
+
+	if blockE != tests[1].expected || localE != tests[4].expected {
+		t.Errorf("wrong symbols defined in blocks. got=%+v, %+v", blockE, localE)
+	}
+	if inner.FreeSymbols[0] != localE {
+		t.Errorf("wrong free symbol. got=%+v", inner.FreeSymbols[0])
+	}
+	if strings.Join(local.Names(), " ") != "a e" {
+		t.Errorf("wrong names of locals. got=%v", local.Names())
+	}
+}
+
+func TestRedefine(t *testing.T) {
+	local := compiler.NewEnclosedSymbolTable(compiler.NewSymbolTable())
+
+	a := local.Define("a")
+	local.Define("b")
+
+	if redefined := local.Define("a"); redefined != a {
+		t.Errorf("redefined symbol is different. want=%+v, got=%+v", a, redefined)
+	}
+
+	if len(local.Names()) != 2 {
+		t.Errorf("wrong number of names. got=%d", len(local.Names()))
+	}
+}
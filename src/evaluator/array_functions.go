@@ -0,0 +1,473 @@
+package evaluator
+
+import (
+	"sort"
+
+	"github.com/ythosa/pukiclang/src/object"
+)
+
+// sequenceType is type of argument of array functions which accept arrays and ranges
+const sequenceType = "ARRAY|RANGE"
+
+// arrayBuiltIns returns built in functions which work with elements of arrays and ranges
+func arrayBuiltIns() []*object.BuiltIn {
+	return []*object.BuiltIn{
+		{Name: "map", HigherOrderFn: mapBuiltIn, Params: []string{sequenceType, "FUNCTION"},
+			Doc: "returns array of results of function called with every element"},
+		{Name: "filter", HigherOrderFn: filter, Params: []string{sequenceType, "FUNCTION"},
+			Doc: "returns array of elements for which function returns truthy value"},
+		{Name: "reduce", HigherOrderFn: reduce, Params: []string{sequenceType, "FUNCTION", "ANY"}, Optional: 1,
+			Doc: "returns accumulator after function is called with accumulator and every element, " +
+				"accumulator starts with initial value or the first element"},
+		{Name: "each", HigherOrderFn: each, Params: []string{sequenceType, "FUNCTION"},
+			Doc: "calls function with every element"},
+		{Name: "find", HigherOrderFn: find, Params: []string{sequenceType, "FUNCTION"},
+			Doc: "returns the first element for which function returns truthy value, null if there is no such element"},
+		{Name: "any", HigherOrderFn: anyBuiltIn, Params: []string{sequenceType, "FUNCTION"}, Optional: 1,
+			Doc: "returns true if function returns truthy value for any element, elements are tested by default"},
+		{Name: "all", HigherOrderFn: all, Params: []string{sequenceType, "FUNCTION"}, Optional: 1,
+			Doc: "returns true if function returns truthy value for every element, elements are tested by default"},
+		{Name: "sort", HigherOrderFn: sortBuiltIn, Params: []string{sequenceType, "FUNCTION"}, Optional: 1,
+			Doc: "returns array of elements in ascending order, function returns true or negative integer " +
+				"if its first argument goes before second"},
+		{Name: "sortBy", HigherOrderFn: sortBy, Params: []string{sequenceType, "FUNCTION"},
+			Doc: "returns array of elements in ascending order of keys which function returns for them"},
+		{Name: "reverse", LimitedFn: reverse, Params: []string{sequenceType},
+			Doc: "returns array of elements in reverse order"},
+		{Name: "zip", LimitedFn: zip, Params: []string{sequenceType}, Variadic: true,
+			Doc: "returns array of arrays of elements with the same index, it is as long as the shortest argument"},
+		{Name: "flatten", LimitedFn: flatten, Params: []string{sequenceType, "INTEGER"}, Optional: 1,
+			Doc: "returns array with nested arrays replaced by their elements up to depth, 1 by default"},
+		{Name: "uniq", LimitedFn: uniq, Params: []string{sequenceType},
+			Doc: "returns array of elements without repeated ones, first occurrences are kept"},
+		{Name: "slice", LimitedFn: sliceArray, Params: []string{sequenceType, "INTEGER", "INTEGER"}, Optional: 1,
+			Doc: "returns elements from start to end exclusive, negative indexes count from the end " +
+				"and indexes out of range are clamped"},
+	}
+}
+
+// elementsOf returns iterator over elements of array or range argument with index i of built in function
+func elementsOf(name string, i int, arg object.Object) (*object.Iterator, *object.Error) {
+	switch arg.(type) {
+	case *object.Array, *object.Range:
+		return newIterator(arg).(*object.Iterator), nil
+	default:
+		return nil, argumentError(name, i, sequenceType, arg)
+	}
+}
+
+// arrayOf returns elements of array or range argument with index i of built in function,
+// range is materialised only if its elements do not exceed allocation limit of execution
+func arrayOf(execution *object.Execution, name string, i int, arg object.Object) ([]object.Object, *object.Error) {
+	switch arg := arg.(type) {
+	case *object.Array:
+		return arg.Elements, nil
+	case *object.Range:
+		if errObj := checkSize(execution, rangeLength(arg)); errObj != nil {
+			return nil, errObj
+		}
+	}
+
+	iterator, errObj := elementsOf(name, i, arg)
+	if errObj != nil {
+		return nil, errObj
+	}
+
+	var elements []object.Object
+	for _, element, ok := iterator.Next(); ok; _, element, ok = iterator.Next() {
+		elements = append(elements, element)
+	}
+
+	return elements, nil
+}
+
+// forEach calls function argument of built in function with every element of sequence argument,
+// visit receives result of the call and returns false to stop iteration
+func forEach(name string, call object.CallFunction, args []object.Object,
+	visit func(element, result object.Object) bool) object.Object {
+	iterator, errObj := elementsOf(name, 0, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	for _, element, ok := iterator.Next(); ok; _, element, ok = iterator.Next() {
+		result := call(args[1], element)
+		if isError(result) {
+			return result
+		}
+
+		if !visit(element, result) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func mapBuiltIn(execution *object.Execution, call object.CallFunction, args ...object.Object) object.Object {
+	if r, ok := args[0].(*object.Range); ok {
+		if errObj := checkSize(execution, rangeLength(r)); errObj != nil {
+			return errObj
+		}
+	}
+
+	results := []object.Object{}
+	if errObj := forEach("map", call, args, func(_, result object.Object) bool {
+		results = append(results, result)
+		return true
+	}); errObj != nil {
+		return errObj
+	}
+
+	return &object.Array{Elements: results}
+}
+
+func filter(execution *object.Execution, call object.CallFunction, args ...object.Object) object.Object {
+	elements := []object.Object{}
+	var limitErr *object.Error
+	if errObj := forEach("filter", call, args, func(element, result object.Object) bool {
+		if !isTruthy(result) {
+			return true
+		}
+
+		if limitErr = checkSize(execution, int64(len(elements)+1)); limitErr != nil {
+			return false
+		}
+		elements = append(elements, element)
+
+		return true
+	}); errObj != nil {
+		return errObj
+	}
+
+	if limitErr != nil {
+		return limitErr
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+func reduce(execution *object.Execution, call object.CallFunction, args ...object.Object) object.Object {
+	iterator, errObj := elementsOf("reduce", 0, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	var accumulator object.Object
+	if len(args) == 3 {
+		accumulator = args[2]
+	} else {
+		_, first, ok := iterator.Next()
+		if !ok {
+			return newError("`reduce` of empty %s without initial value", args[0].Type())
+		}
+		accumulator = first
+	}
+
+	for _, element, ok := iterator.Next(); ok; _, element, ok = iterator.Next() {
+		accumulator = call(args[1], accumulator, element)
+		if isError(accumulator) {
+			return accumulator
+		}
+	}
+
+	return accumulator
+}
+
+func each(execution *object.Execution, call object.CallFunction, args ...object.Object) object.Object {
+	if errObj := forEach("each", call, args, func(_, _ object.Object) bool {
+		return true
+	}); errObj != nil {
+		return errObj
+	}
+
+	return NULL
+}
+
+func find(execution *object.Execution, call object.CallFunction, args ...object.Object) object.Object {
+	var found object.Object = NULL
+	if errObj := forEach("find", call, args, func(element, result object.Object) bool {
+		if isTruthy(result) {
+			found = element
+			return false
+		}
+		return true
+	}); errObj != nil {
+		return errObj
+	}
+
+	return found
+}
+
+func anyBuiltIn(execution *object.Execution, call object.CallFunction, args ...object.Object) object.Object {
+	return test("any", call, args, true)
+}
+
+func all(execution *object.Execution, call object.CallFunction, args ...object.Object) object.Object {
+	return test("all", call, args, false)
+}
+
+// test returns stop if element with truthiness equal to stop is found, test function is identity by default
+func test(name string, call object.CallFunction, args []object.Object, stop bool) object.Object {
+	if len(args) == 1 {
+		identity := &object.BuiltIn{Fn: func(args ...object.Object) object.Object {
+			return args[0]
+		}}
+		args = []object.Object{args[0], identity}
+	}
+
+	result := !stop
+	if errObj := forEach(name, call, args, func(_, tested object.Object) bool {
+		if isTruthy(tested) == stop {
+			result = stop
+			return false
+		}
+		return true
+	}); errObj != nil {
+		return errObj
+	}
+
+	return nativeBoolToBooleanObject(result)
+}
+
+func sortBuiltIn(execution *object.Execution, call object.CallFunction, args ...object.Object) object.Object {
+	elements, errObj := arrayOf(execution, "sort", 0, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	less := naturalLess
+	if len(args) == 2 {
+		less = func(a, b object.Object) object.Object {
+			return call(args[1], a, b)
+		}
+	}
+
+	return sortElements(elements, elements, less)
+}
+
+func sortBy(execution *object.Execution, call object.CallFunction, args ...object.Object) object.Object {
+	elements, errObj := arrayOf(execution, "sortBy", 0, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	keys := make([]object.Object, len(elements))
+	for i, element := range elements {
+		keys[i] = call(args[1], element)
+		if isError(keys[i]) {
+			return keys[i]
+		}
+	}
+
+	return sortElements(elements, keys, naturalLess)
+}
+
+// naturalLess returns true if a is less than b in natural order of objects, or error if they are not ordered
+func naturalLess(a, b object.Object) object.Object {
+	result, err := object.Compare(a, b)
+	if err != nil {
+		return newError("%s", err)
+	}
+
+	return nativeBoolToBooleanObject(result < 0)
+}
+
+// sortElements returns array of elements stably sorted by their keys, less returns true if a goes before b
+// or negative integer for three-way comparison, the first error of less stops sorting
+func sortElements(elements, keys []object.Object, less func(a, b object.Object) object.Object) object.Object {
+	indexes := make([]int, len(elements))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	var errObj object.Object
+	sort.SliceStable(indexes, func(i, j int) bool {
+		if errObj != nil {
+			return false
+		}
+
+		result := less(keys[indexes[i]], keys[indexes[j]])
+		if isError(result) {
+			errObj = result
+			return false
+		}
+
+		switch result := result.(type) {
+		case *object.Boolean:
+			return result.Value
+		case *object.Integer:
+			return result.Value < 0
+		default:
+			errObj = newError("comparator of `sort` must return BOOLEAN or INTEGER, got %s", result.Type())
+			return false
+		}
+	})
+
+	if errObj != nil {
+		return errObj
+	}
+
+	sorted := make([]object.Object, len(elements))
+	for i, index := range indexes {
+		sorted[i] = elements[index]
+	}
+
+	return &object.Array{Elements: sorted}
+}
+
+func reverse(execution *object.Execution, args ...object.Object) object.Object {
+	elements, errObj := arrayOf(execution, "reverse", 0, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	reversed := make([]object.Object, len(elements))
+	for i, element := range elements {
+		reversed[len(elements)-1-i] = element
+	}
+
+	return &object.Array{Elements: reversed}
+}
+
+func zip(execution *object.Execution, args ...object.Object) object.Object {
+	sequences := make([][]object.Object, len(args))
+	length := -1
+
+	for i, arg := range args {
+		elements, errObj := arrayOf(execution, "zip", i, arg)
+		if errObj != nil {
+			return errObj
+		}
+
+		sequences[i] = elements
+		if length < 0 || len(elements) < length {
+			length = len(elements)
+		}
+	}
+
+	tuples := make([]object.Object, length)
+	for i := range tuples {
+		tuple := make([]object.Object, len(sequences))
+		for j, elements := range sequences {
+			tuple[j] = elements[i]
+		}
+
+		tuples[i] = &object.Array{Elements: tuple}
+	}
+
+	return &object.Array{Elements: tuples}
+}
+
+func flatten(execution *object.Execution, args ...object.Object) object.Object {
+	elements, errObj := arrayOf(execution, "flatten", 0, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	depth := int64(1)
+	if len(args) == 2 {
+		integer, ok := args[1].(*object.Integer)
+		if !ok {
+			return argumentError("flatten", 1, object.IntegerObj, args[1])
+		}
+		if integer.Value < 0 {
+			return newError("depth of `flatten` must not be negative, got %d", integer.Value)
+		}
+		depth = integer.Value
+	}
+
+	flat, ok := flattenElements([]object.Object{}, elements, depth, map[*object.Array]bool{})
+	if !ok {
+		return newError("cannot flatten array which contains itself")
+	}
+
+	return &object.Array{Elements: flat}
+}
+
+// flattenElements appends elements to flat with nested arrays flattened up to depth,
+// visiting are arrays which are being flattened, it returns false if array contains itself
+func flattenElements(flat, elements []object.Object, depth int64, visiting map[*object.Array]bool) ([]object.Object, bool) {
+	for _, element := range elements {
+		arr, isArray := element.(*object.Array)
+		if !isArray || depth == 0 {
+			flat = append(flat, element)
+			continue
+		}
+
+		if visiting[arr] {
+			return nil, false
+		}
+
+		visiting[arr] = true
+		var ok bool
+		if flat, ok = flattenElements(flat, arr.Elements, depth-1, visiting); !ok {
+			return nil, false
+		}
+		delete(visiting, arr)
+	}
+
+	return flat, true
+}
+
+func uniq(execution *object.Execution, args ...object.Object) object.Object {
+	elements, errObj := arrayOf(execution, "uniq", 0, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	seen := object.NewHash()
+	unique := []object.Object{}
+
+	for _, element := range elements {
+		if _, ok := seen.Get(element); ok {
+			continue
+		}
+
+		// objects which can not be keys of hash are compared with every unique element
+		if !seen.Set(element, TRUE) && containsEqual(unique, element) {
+			continue
+		}
+
+		unique = append(unique, element)
+	}
+
+	return &object.Array{Elements: unique}
+}
+
+// containsEqual returns true if elements contain object which is structurally equal to element
+func containsEqual(elements []object.Object, element object.Object) bool {
+	for _, e := range elements {
+		if object.Equal(e, element) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sliceArray(execution *object.Execution, args ...object.Object) object.Object {
+	elements, errObj := arrayOf(execution, "slice", 0, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	indexes := []int64{0, int64(len(elements))}
+	for i, arg := range args[1:] {
+		index, ok := arg.(*object.Integer)
+		if !ok {
+			return argumentError("slice", i+1, object.IntegerObj, arg)
+		}
+		indexes[i] = index.Value
+	}
+
+	from, to := clampIndex(indexes[0], len(elements)), clampIndex(indexes[1], len(elements))
+	if from > to {
+		return &object.Array{Elements: []object.Object{}}
+	}
+
+	sliced := make([]object.Object, to-from)
+	copy(sliced, elements[from:to])
+
+	return &object.Array{Elements: sliced}
+}
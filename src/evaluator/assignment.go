@@ -0,0 +1,95 @@
+package evaluator
+
+import (
+	"github.com/ythosa/pukiclang/src/ast"
+	"github.com/ythosa/pukiclang/src/object"
+)
+
+func evalAssignExpression(ae *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := ae.Target.(type) {
+	case *ast.Identifier:
+		var current object.Object
+		if ae.Operator != "" {
+			current = Eval(target, env)
+			if isError(current) {
+				return current
+			}
+		}
+
+		value := evalAssignedValue(ae, current, env)
+		if isError(value) {
+			return value
+		}
+
+		if !env.Assign(target.Value, value) {
+			return withPosition(newError("assignment to undefined variable: %s", target.Value), ae)
+		}
+
+		return value
+
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+
+		var current object.Object
+		if ae.Operator != "" {
+			current = withPosition(evalIndexExpression(left, index), target)
+			if isError(current) {
+				return current
+			}
+		}
+
+		value := evalAssignedValue(ae, current, env)
+		if isError(value) {
+			return value
+		}
+
+		return withPosition(evalIndexAssignment(left, index, value), ae)
+
+	default:
+		return withPosition(newError("cannot assign to %s", ae.Target.String()), ae)
+	}
+}
+
+// evalAssignedValue evaluates value of assignment, compound assignment combines it with current value
+func evalAssignedValue(ae *ast.AssignExpression, current object.Object, env *object.Environment) object.Object {
+	value := Eval(ae.Value, env)
+	if isError(value) || ae.Operator == "" {
+		return value
+	}
+
+	return withPosition(evalLimitedInfixExpression(env.Execution(), ae.Operator, current, value), ae)
+}
+
+func evalIndexAssignment(left, index, value object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newError("index of array must be INTEGER, got %s", index.Type())
+		}
+
+		if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+			return newError("index out of range: %d with length %d", idx.Value, len(left.Elements))
+		}
+
+		left.Elements[idx.Value] = value
+
+	case *object.Hash:
+		if !left.Set(index, value) {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+
+	return value
+}
@@ -1,27 +1,92 @@
 package evaluator
 
 import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"unicode/utf8"
+
 	"github.com/ythosa/pukiclang/src/object"
 )
 
-var builtIns = map[string]*object.BuiltIn{
-	"len":   &object.BuiltIn{Fn: lenBuiltIn},
-	"first": &object.BuiltIn{Fn: first},
-	"last":  &object.BuiltIn{Fn: last},
-	"tail":  &object.BuiltIn{Fn: tail},
-	"push":  &object.BuiltIn{Fn: push},
-	"sum":   &object.BuiltIn{Fn: sum},
+// defaultBuiltIns is registry of built in functions for environments which do not have their own registry
+var defaultBuiltIns = NewBuiltIns()
+
+// NewBuiltIns returns new registry with standard built in functions
+func NewBuiltIns() *object.Registry {
+	registry := object.NewRegistry()
+
+	builtIns := []*object.BuiltIn{
+		{Name: "len", Fn: lenBuiltIn, Params: []string{"STRING|ARRAY|HASH"},
+			Doc: "returns number of characters of string, elements of array or pairs of hash"},
+		{Name: "first", Fn: first, Params: []string{"STRING|ARRAY"},
+			Doc: "returns first character of string or first element of array, null if it is empty"},
+		{Name: "last", Fn: last, Params: []string{"STRING|ARRAY"},
+			Doc: "returns last character of string or last element of array, null if it is empty"},
+		{Name: "tail", Fn: tail, Params: []string{"STRING|ARRAY"},
+			Doc: "returns string or array without its first element, null if it is empty"},
+		{Name: "push", Fn: push, Params: []string{"ARRAY", "ANY"},
+			Doc: "returns new array with value appended to elements of array"},
+		{Name: "sum", Fn: sum, Params: []string{"ARRAY"},
+			Doc: "returns sum of numbers in array"},
+		{Name: "puts", LimitedFn: puts, Params: []string{"ANY"}, Optional: 1, Variadic: true,
+			Doc: "prints values, one per line"},
+		{Name: "bytes", Fn: bytesBuiltIn, Params: []string{"STRING"},
+			Doc: "returns array of bytes of string"},
+		{Name: "range", Fn: rangeBuiltIn, Params: []string{"INTEGER", "INTEGER", "INTEGER"}, Optional: 2,
+			Doc: "returns integers from start to end exclusive: range(end), range(start, end) or range(start, end, step)"},
+		roundingBuiltIn("floor", math.Floor, "returns the greatest integral number less than or equal to number"),
+		roundingBuiltIn("ceil", math.Ceil, "returns the least integral number greater than or equal to number"),
+		roundingBuiltIn("round", math.Round, "returns integral number nearest to number, rounding half away from zero"),
+		{Name: "sqrt", Fn: sqrt, Params: []string{"INTEGER|FLOAT"},
+			Doc: "returns square root of number"},
+		{Name: "pow", LimitedFn: pow, Params: []string{"INTEGER|FLOAT", "INTEGER|FLOAT"},
+			Doc: "returns base raised to the power of exponent"},
+		{Name: "abs", Fn: abs, Params: []string{"INTEGER|FLOAT"},
+			Doc: "returns absolute value of number"},
+		extremumBuiltIn("min", "<", "returns the least of numbers or of numbers in array"),
+		extremumBuiltIn("max", ">", "returns the greatest of numbers or of numbers in array"),
+		{Name: "builtins", Fn: builtInsBuiltIn(registry),
+			Doc: "returns array of hashes with name, signature and doc of every available built in function"},
+	}
+
+	builtIns = append(builtIns, arrayBuiltIns()...)
+	builtIns = append(builtIns, hashBuiltIns()...)
+	builtIns = append(builtIns, stringBuiltIns()...)
+
+	for _, builtIn := range builtIns {
+		if err := registry.Register(builtIn); err != nil {
+			panic(err)
+		}
+	}
+
+	return registry
 }
 
-func lenBuiltIn(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
+// registryOf returns registry of built in functions which are available in environment
+func registryOf(env *object.Environment) *object.Registry {
+	if registry := env.BuiltIns(); registry != nil {
+		return registry
 	}
 
+	return defaultBuiltIns
+}
+
+// outputOf returns writer which values printed within execution are written to
+func outputOf(execution *object.Execution) io.Writer {
+	if execution == nil || execution.Output == nil {
+		return os.Stdout
+	}
+
+	return execution.Output
+}
+
+func lenBuiltIn(args ...object.Object) object.Object {
 	switch arg := args[0].(type) {
 	case *object.String:
 		return &object.Integer{
-			Value: int64(len(arg.Value)),
+			Value: int64(utf8.RuneCountInString(arg.Value)),
 		}
 
 	case *object.Array:
@@ -29,6 +94,11 @@ func lenBuiltIn(args ...object.Object) object.Object {
 			Value: int64(len(arg.Elements)),
 		}
 
+	case *object.Hash:
+		return &object.Integer{
+			Value: int64(arg.Len()),
+		}
+
 	default:
 		return newError("argument to `len` not supported, got %s",
 			args[0].Type())
@@ -36,15 +106,12 @@ func lenBuiltIn(args ...object.Object) object.Object {
 }
 
 func first(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
-	}
-
 	switch arg := args[0].(type) {
 	case *object.String:
 		if len(arg.Value) > 0 {
+			ch, _ := utf8.DecodeRuneInString(arg.Value)
 			return &object.String{
-				Value: string(arg.Value[0]),
+				Value: string(ch),
 			}
 		}
 		return NULL
@@ -63,15 +130,12 @@ func first(args ...object.Object) object.Object {
 }
 
 func last(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
-	}
-
 	switch arg := args[0].(type) {
 	case *object.String:
 		if len(arg.Value) > 0 {
+			ch, _ := utf8.DecodeLastRuneInString(arg.Value)
 			return &object.String{
-				Value: string(arg.Value[len(arg.Value)-1]),
+				Value: string(ch),
 			}
 		}
 		return NULL
@@ -90,19 +154,12 @@ func last(args ...object.Object) object.Object {
 }
 
 func tail(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
-	}
-
 	switch arg := args[0].(type) {
 	case *object.String:
-		length := len(arg.Value)
-		if length > 0 {
-			tailOfString := make([]byte, length-1, length-1)
-
-			copy([]byte(tailOfString), []byte(arg.Value[1:]))
+		if len(arg.Value) > 0 {
+			_, width := utf8.DecodeRuneInString(arg.Value)
 
-			return &object.String{Value: string(tailOfString)}
+			return &object.String{Value: arg.Value[width:]}
 		}
 		return NULL
 
@@ -125,11 +182,6 @@ func tail(args ...object.Object) object.Object {
 }
 
 func push(args ...object.Object) object.Object {
-	if len(args) != 2 {
-		return newError("wrong number of arguments. got=%d, want=2",
-			len(args))
-	}
-
 	if args[0].Type() != object.ArrayObj {
 		return newError("argument to `push` must be ARRAY, got %s",
 			args[0].Type())
@@ -146,28 +198,94 @@ func push(args ...object.Object) object.Object {
 }
 
 func sum(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1",
-			len(args))
-	}
-
 	if args[0].Type() != object.ArrayObj {
 		return newError("first argument to `sum` must be ARRAY, got %s",
 			args[0].Type())
 	}
 
-	var sum object.Integer
+	var sum object.Object = &object.Integer{Value: 0}
 
 	elements := args[0].(*object.Array).Elements
 	for _, e := range elements {
-		switch e.Type() {
-		case object.IntegerObj:
-			sum.Value += e.(*object.Integer).Value
-		default:
+		if !isNumber(e) {
 			return newError("unsupported type to `sum`, got %s",
 				e.Type())
 		}
+
+		sum = evalInfixExpression("+", sum, e)
+	}
+
+	return sum
+}
+
+func puts(execution *object.Execution, args ...object.Object) object.Object {
+	out := outputOf(execution)
+	for _, arg := range args {
+		fmt.Fprintln(out, arg.Inspect())
+	}
+
+	return NULL
+}
+
+func bytesBuiltIn(args ...object.Object) object.Object {
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newError("argument to `bytes` must be STRING, got %s",
+			args[0].Type())
+	}
+
+	elements := make([]object.Object, len(str.Value))
+	for i := 0; i < len(str.Value); i++ {
+		elements[i] = &object.Integer{Value: int64(str.Value[i])}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// rangeBuiltIn returns range of integers: range(end), range(start, end) or range(start, end, step)
+func rangeBuiltIn(args ...object.Object) object.Object {
+	bounds := make([]int64, len(args))
+	for i, arg := range args {
+		integer, ok := arg.(*object.Integer)
+		if !ok {
+			return newError("arguments to `range` must be INTEGER, got %s", arg.Type())
+		}
+
+		bounds[i] = integer.Value
+	}
+
+	r := &object.Range{Step: 1}
+	switch len(bounds) {
+	case 1:
+		r.End = bounds[0]
+	case 2:
+		r.Start, r.End = bounds[0], bounds[1]
+	case 3:
+		r.Start, r.End, r.Step = bounds[0], bounds[1], bounds[2]
+	}
+
+	if r.Step == 0 {
+		return newError("step of `range` must not be zero")
 	}
 
-	return &sum
+	return r
+}
+
+// builtInsBuiltIn returns builtin which describes built in functions of registry
+func builtInsBuiltIn(registry *object.Registry) object.BuiltInFunction {
+	return func(args ...object.Object) object.Object {
+		builtIns := registry.BuiltIns()
+
+		elements := make([]object.Object, len(builtIns))
+		for i, builtIn := range builtIns {
+			hash := object.NewHash()
+			setHashValue(hash, "name", &object.String{Value: builtIn.Name})
+			setHashValue(hash, "signature", &object.String{Value: builtIn.Signature()})
+			setHashValue(hash, "doc", &object.String{Value: builtIn.Doc})
+
+			elements[i] = hash
+		}
+
+		return &object.Array{Elements: elements}
+	}
 }
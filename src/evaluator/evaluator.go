@@ -2,20 +2,38 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 
 	"github.com/ythosa/pukiclang/src/ast"
 	"github.com/ythosa/pukiclang/src/object"
+	"github.com/ythosa/pukiclang/src/token"
 )
 
 // Permanent references to objects that will not change
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL     = &object.Null{}
+	TRUE     = &object.Boolean{Value: true}
+	FALSE    = &object.Boolean{Value: false}
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 // Eval evals node of AST tree
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	if err := step(env); err != nil {
+		return withPosition(err, node)
+	}
+
+	result := evalNode(node, env)
+	if err := checkAllocation(env, result); err != nil {
+		return withPosition(err, node)
+	}
+
+	return result
+}
+
+func evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
@@ -30,6 +48,16 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			Value: node.Value,
 		}
 
+	case *ast.BigIntegerLiteral:
+		return &object.BigInt{
+			Value: node.Value,
+		}
+
+	case *ast.FloatLiteral:
+		return &object.Float{
+			Value: node.Value,
+		}
+
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
@@ -44,9 +72,13 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return right
 		}
 
-		return evalPrefixExpression(node.Operator, right)
+		return withPosition(evalPrefixExpression(node.Operator, right), node)
 
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
+
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
@@ -57,7 +89,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return right
 		}
 
-		return evalInfixExpression(node.Operator, left, right)
+		return withPosition(evalLimitedInfixExpression(env.Execution(), node.Operator, left, right), node)
 
 	case *ast.BlockStatement:
 		return evalBlockStatement(node.Statements, env)
@@ -65,6 +97,38 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+
+	case *ast.ImportExpression:
+		return evalImportExpression(node, env)
+
+	case *ast.ExportStatement:
+		return Eval(node.Statement, env)
+
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+
+	case *ast.WhileExpression:
+		return evalWhileExpression(node, env)
+
+	case *ast.ForExpression:
+		return evalForExpression(node, env)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+
+		return withPosition(throwValue(val), node)
+
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
 		if isError(val) {
@@ -74,7 +138,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.ReturnValue{Value: val}
 
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return withPosition(evalIdentifier(node, env), node)
 
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
@@ -92,6 +156,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			Parameters: params,
 			Body:       body,
 			Env:        env,
+			Name:       node.Name,
 		}
 
 	case *ast.CallExpression:
@@ -105,7 +170,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		return withPosition(applyFunction(function, args, env.Execution(), node.Pos()), node)
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
@@ -116,7 +181,10 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.Array{Elements: elements}
 
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return withPosition(evalHashLiteral(node, env), node)
+
+	case *ast.BadStatement, *ast.BadExpression:
+		return withPosition(newError("invalid syntax"), node)
 
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
@@ -129,7 +197,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return index
 		}
 
-		return evalIndexExpression(left, index)
+		return withPosition(evalIndexExpression(left, index), node)
 	}
 
 	return nil
@@ -160,7 +228,8 @@ func evalBlockStatement(stmts []ast.Statement, env *object.Environment) object.O
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.ReturnValueObj || rt == object.ErrorObj {
+			if rt == object.ReturnValueObj || rt == object.ErrorObj ||
+				rt == object.BreakObj || rt == object.ContinueObj {
 				return result
 			}
 		}
@@ -201,25 +270,35 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.IntegerObj {
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 {
+			return normalizeBigInt(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: -right.Value}
+	case *object.BigInt:
+		return normalizeBigInt(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-
-	return &object.Integer{Value: -value}
 }
 
 func evalInfixExpression(operator string, left object.Object, right object.Object) object.Object {
 	switch {
-	case left.Type() == object.IntegerObj && right.Type() == object.IntegerObj:
+	case isInteger(left) && isInteger(right):
 		return evalInfixIntegerExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalInfixFloatExpression(operator, left, right)
 	case left.Type() == object.StringObj && right.Type() == object.StringObj:
 		return evalInfixStringExpression(operator, left, right)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
+	case left.Type() == object.ArrayObj && right.Type() == object.ArrayObj:
+		return evalInfixArrayExpression(operator, left, right)
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
@@ -227,23 +306,34 @@ func evalInfixExpression(operator string, left object.Object, right object.Objec
 	}
 }
 
-func evalInfixIntegerExpression(
+// evalInfixFloatExpression evaluates infix expression with float operand, integer operand is promoted to float
+func evalInfixFloatExpression(
 	operator string,
 	left object.Object,
 	right object.Object,
 ) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
 
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		return &object.Float{Value: leftVal + rightVal}
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		return &object.Float{Value: leftVal - rightVal}
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		return &object.Float{Value: leftVal * rightVal}
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -266,14 +356,75 @@ func evalInfixStringExpression(
 	left object.Object,
 	right object.Object,
 ) object.Object {
-	if operator != "+" {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+
+	// strings are compared lexicographically by bytes, i.e. by code points of characters
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
+}
 
-	leftVal := left.(*object.String).Value
-	rightVal := right.(*object.String).Value
+// evalInfixArrayExpression evaluates infix expression with array operands, arrays are ordered lexicographically
+func evalInfixArrayExpression(
+	operator string,
+	left object.Object,
+	right object.Object,
+) object.Object {
+	switch operator {
+	case "<", ">", "<=", ">=":
+		result, err := object.Compare(left, right)
+		if err != nil {
+			return newError("%s", err)
+		}
+
+		return nativeBoolToBooleanObject(compareResult(operator, result))
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// compareResult returns result of comparison operator for result of object.Compare
+func compareResult(operator string, result int) bool {
+	switch operator {
+	case "<":
+		return result < 0
+	case ">":
+		return result > 0
+	case "<=":
+		return result <= 0
+	default:
+		return result >= 0
+	}
+}
+
+// evalLogicalExpression evaluates && and || with short circuit, it returns operand which decides result
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	if isTruthy(left) == (node.Operator == "||") {
+		return left
+	}
 
-	return &object.String{Value: leftVal + rightVal}
+	return Eval(node.Right, env)
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
@@ -309,7 +460,7 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return val
 	}
 
-	if builtIn, ok := builtIns[node.Value]; ok {
+	if builtIn, ok := registryOf(env).Lookup(node.Value); ok {
 		return builtIn
 	}
 
@@ -328,21 +479,50 @@ func evalExpressions(
 			return []object.Object{evaluated}
 		}
 
-		results = append(results, Eval(e, env))
+		results = append(results, evaluated)
 	}
 
 	return results
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// applyFunction calls function with passed arguments, execution of the caller limits built in functions
+// and callSite is position of the call which is recorded in stack trace of error raised inside the function
+func applyFunction(
+	fn object.Object,
+	args []object.Object,
+	execution *object.Execution,
+	callSite token.Position,
+) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
+		if len(args) != len(fn.Parameters) {
+			return newError("wrong number of arguments: want=%d, got=%d",
+				len(fn.Parameters), len(args))
+		}
+
+		if err := enterCall(fn.Env); err != nil {
+			return err
+		}
+
 		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		evaluated := unwrapReturnValue(Eval(fn.Body, extendedEnv))
+		leaveCall(fn.Env)
+
+		if err, ok := evaluated.(*object.Error); ok {
+			err.Stack = append(err.Stack, object.StackFrame{Function: fn.Name, Pos: callSite})
+		}
+
+		return evaluated
 
 	case *object.BuiltIn:
-		return fn.Fn(args...)
+		return fn.Apply(execution, func(fn object.Object, args ...object.Object) object.Object {
+			result := unwrapReturnValue(applyFunction(fn, args, execution, callSite))
+			if result == nil {
+				return NULL
+			}
+
+			return result
+		}, args...)
 
 	default:
 		return newError("not a function: %s", fn.Type())
@@ -378,6 +558,8 @@ func evalIndexExpression(left, index object.Object) object.Object {
 		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HashObj:
 		return evalHashIndexExpression(left, index)
+	case left.Type() == object.ModuleObj:
+		return evalModuleIndexExpression(left, index)
 	default:
 		return newError("index operator not supported: %s", left.Type())
 	}
@@ -396,26 +578,24 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 }
 
 func evalStringIndexExpression(str, index object.Object) object.Object {
-	stringObject := str.(*object.String)
+	chars := []rune(str.(*object.String).Value)
 	idx := index.(*object.Integer).Value
-	max := int64(len(stringObject.Value))
+	max := int64(len(chars) - 1)
 
 	if idx < 0 || idx > max {
 		return NULL
 	}
 
-	value := string(stringObject.Value[idx])
-	return &object.String{Value: value}
+	return &object.String{Value: string(chars[idx])}
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
-	key, ok := index.(object.Hashable)
-	if !ok {
+	if _, ok := object.HashKeyOf(index); !ok {
 		return newError("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	pair, ok := hashObject.Get(index)
 	if !ok {
 		return NULL
 	}
@@ -427,38 +607,42 @@ func evalHashLiteral(
 	node *ast.HashLiteral,
 	env *object.Environment,
 ) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
-		if !ok {
+		if _, ok := object.HashKeyOf(key); !ok {
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(pair.Value, env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{
-			Key:   key,
-			Value: value,
-		}
+		hash.Set(key, value)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+// withPosition stamps position of the node on the error if it has not been located yet
+func withPosition(obj object.Object, node ast.Node) object.Object {
+	if err, ok := obj.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+
+	return obj
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ErrorObj
@@ -466,3 +650,11 @@ func isError(obj object.Object) bool {
 
 	return false
 }
+
+func isReturnValue(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.ReturnValueObj
+	}
+
+	return false
+}
@@ -1,12 +1,21 @@
 package evaluator_test
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ythosa/pukiclang/src/ast"
+	"github.com/ythosa/pukiclang/src/compiler"
 	"github.com/ythosa/pukiclang/src/evaluator"
 	"github.com/ythosa/pukiclang/src/lexer"
 	"github.com/ythosa/pukiclang/src/object"
 	"github.com/ythosa/pukiclang/src/parser"
+	"github.com/ythosa/pukiclang/src/vm"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -33,14 +42,82 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"-7 / 2", -3},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"2 * 3 ** 2", 18},
+		{"let x = 10; x %= 4; x", 2},
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		testIntegerObject(t, evaluated, tt.expected)
 	}
 }
 
+func TestBigIntegers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"4611686018427387904 * 4", "18446744073709551616"},
+		{"2 ** 100", "1267650600228229401496703205376"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+		{"abs(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"2 ** 64 % 1000", "616"},
+		{"(2 ** 64) / (2 ** 60)", "16"},
+		{"2 ** 64 > 2 ** 63", "true"},
+		{"2 ** 64 == 18446744073709551616", "true"},
+		{"max(2 ** 64, 1, 2 ** 65)", "36893488147419103232"},
+		{"sum([9223372036854775807, 1])", "9223372036854775808"},
+		{"2 ** 64 * 0.5", "9.223372036854776e+18"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%s, got=%s", tt.input, tt.expected, inspect(evaluated))
+		}
+	}
+
+	demoted := testEval(t, "(2 ** 64) / (2 ** 62)")
+	testIntegerObject(t, demoted, 4)
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5", 1.5},
+		{".5", 0.5},
+		{"1e3", 1000},
+		{"2.5e-1", 0.25},
+		{"-1.5", -1.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2},
+		{"7 / 2.0", 3.5},
+		{"10 - 2.5 * 2", 5},
+		{"let xs = [1, 2, 4]; sum(xs) * 1.0 / len(xs)", 7.0 / 3},
+		{"sum([1, 0.5])", 1.5},
+		{"7.5 % 2", 1.5},
+		{"2 ** -1", 0.5},
+		{"4 ** 0.5", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -69,14 +146,79 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"1 <= 1", true},
 		{"1 >= 2", false},
 		{"1 <= 0", false},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1 == 1.0", true},
+		{"0.1 + 0.2 == 0.3", false},
+		{"2.5 != 2.5", false},
+		{"1.5 >= 1.5", true},
+		{`"a" < "b"`, true},
+		{`"abc" > "abd"`, false},
+		{`"ab" < "abc"`, true},
+		{`"b" >= "abc"`, true},
+		{`"a" <= "a"`, true},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"Z" < "a"`, true},
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] == [1, 2, 3]", false},
+		{"[1] == [1.0]", true},
+		{`[1] == ["1"]`, false},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"b": 1}`, false},
+		{"{} == {}", true},
+		{"range(3) == range(0, 3)", true},
+		{"let f = fn() { 1 }; f == f", true},
+		{"fn() { 1 } == fn() { 1 }", false},
+		{"[1, 2] < [1, 3]", true},
+		{"[1, 2] < [1, 2, 0]", true},
+		{"[2] > [1, 5]", true},
+		{"[1, 2] <= [1, 2]", true},
+		{`[[1, "b"]] >= [[1, "a"]]`, true},
+		{"[] < [1]", true},
+		{"let a = [1]; a[0] = a; let b = [1]; b[0] = b; a == b", true},
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		testBooleanObject(t, evaluated, tt.expected)
 	}
 }
 
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"true && true", "true"},
+		{"true && false", "false"},
+		{"false || true", "true"},
+		{"false || false", "false"},
+		{"1 && 2", "2"},
+		{"if (false) { 1 } && 2", "null"},
+		{"false && 2", "false"},
+		{"0 || 2", "0"},
+		{`false || "default"`, "default"},
+		{"1 < 2 && 2 < 3", "true"},
+		{"false && undefinedVariable", "false"},
+		{"true || 1 / 0", "true"},
+		{"let calls = 0; let f = fn() { calls += 1; true }; false && f(); true || f(); calls", "0"},
+		{"let calls = 0; let f = fn() { calls += 1; true }; true && f(); false || f(); calls", "2"},
+		{"let i = 0; while (i < 10 && i != 3) { i += 1 }; i", "3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%s, got=%s", tt.input, tt.expected, inspect(evaluated))
+		}
+	}
+}
+
 func TestBangOperator(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -91,7 +233,7 @@ func TestBangOperator(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		testBooleanObject(t, evaluated, tt.expected)
 	}
 }
@@ -111,7 +253,7 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		integer, ok := tt.expected.(int)
 		if ok {
 			testIntegerObject(t, evaluated, int64(integer))
@@ -138,18 +280,104 @@ func TestReturnStatements(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		testIntegerObject(t, evaluated, tt.expected)
 	}
 }
 
-func testEval(input string) object.Object {
+func testEval(t *testing.T, input string) object.Object {
+	t.Helper()
+
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
 	env := object.NewEnvironment()
 
-	return evaluator.Eval(program, env)
+	evaluated := evaluator.Eval(program, env)
+	testVM(t, input, program, evaluated)
+
+	return evaluated
+}
+
+// testVM checks that the vm backend produces the same result as the evaluator
+func testVM(t *testing.T, input string, program *ast.Program, expected object.Object) {
+	t.Helper()
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Errorf("compiler error for %q: %s", input, err)
+		return
+	}
+
+	machine := vm.New(comp.Bytecode())
+	if err := machine.Run(); err != nil {
+		t.Errorf("vm error for %q: %s", input, err)
+		return
+	}
+
+	if !sameObjects(expected, machine.Result()) {
+		t.Errorf("vm result is different for %q. eval=%s, vm=%s",
+			input, inspect(expected), inspect(machine.Result()))
+	}
+}
+
+func sameObjects(expected, actual object.Object) bool {
+	switch expected := expected.(type) {
+	case nil, *object.Null:
+		return actual == nil || actual == evaluator.NULL
+
+	case *object.Function:
+		_, ok := actual.(*object.Closure)
+		return ok
+
+	case *object.Error:
+		actual, ok := actual.(*object.Error)
+		return ok && expected.Traceback() == actual.Traceback()
+
+	case *object.Array:
+		actual, ok := actual.(*object.Array)
+		if !ok || len(expected.Elements) != len(actual.Elements) {
+			return false
+		}
+
+		for i := range expected.Elements {
+			if !sameObjects(expected.Elements[i], actual.Elements[i]) {
+				return false
+			}
+		}
+
+		return true
+
+	case *object.Hash:
+		actual, ok := actual.(*object.Hash)
+		if !ok || expected.Len() != actual.Len() {
+			return false
+		}
+
+		actualPairs := actual.Pairs()
+		for i, pair := range expected.Pairs() {
+			if !sameObjects(pair.Key, actualPairs[i].Key) || !sameObjects(pair.Value, actualPairs[i].Value) {
+				return false
+			}
+		}
+
+		return true
+
+	default:
+		return actual != nil && expected.Type() == actual.Type() &&
+			expected.Inspect() == actual.Inspect()
+	}
+}
+
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return "nil"
+	}
+	if errObj, ok := obj.(*object.Error); ok {
+		return errObj.Traceback()
+	}
+
+	return obj.Inspect()
 }
 
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
@@ -167,6 +395,21 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g",
+			result.Value, expected)
+	}
+
+	return true
+}
+
 func testStringObject(t *testing.T, obj object.Object, expected string) bool {
 	result, ok := obj.(*object.String)
 	if !ok {
@@ -223,6 +466,38 @@ func TestErrorHandling(t *testing.T) {
 			"-true",
 			"unknown operator: -BOOLEAN",
 		},
+		{
+			`[1] < ["a"]`,
+			"cannot compare INTEGER with STRING",
+		},
+		{
+			"[true] < [false]",
+			"BOOLEAN is not ordered",
+		},
+		{
+			"[1] + [2]",
+			"unknown operator: ARRAY + ARRAY",
+		},
+		{
+			"{} < {}",
+			"unknown operator: HASH < HASH",
+		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"5 % 0",
+			"division by zero",
+		},
+		{
+			"1.5 / 0",
+			"division by zero",
+		},
+		{
+			"9223372036854775808 * 2 / 0",
+			"division by zero",
+		},
 		{
 			"true + false;",
 			"unknown operator: BOOLEAN + BOOLEAN",
@@ -258,10 +533,46 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"fn(a, b) { a }(1)",
+			"wrong number of arguments: want=2, got=1",
+		},
+		{
+			"x = 1",
+			"assignment to undefined variable: x",
+		},
+		{
+			"let f = fn() { y = 1 }; f()",
+			"assignment to undefined variable: y",
+		},
+		{
+			"x += 1",
+			"identifier not found: x",
+		},
+		{
+			"let a = [1]; a[5] = 1",
+			"index out of range: 5 with length 1",
+		},
+		{
+			`let a = [1]; a["0"] = 1`,
+			"index of array must be INTEGER, got STRING",
+		},
+		{
+			`let s = "ab"; s[0] = "c"`,
+			"index assignment not supported: STRING",
+		},
+		{
+			"let h = {}; h[fn() {}] = 1",
+			"unusable as hash key: FUNCTION",
+		},
+		{
+			`let x = 1; x += "a"`,
+			"type mismatch: INTEGER + STRING",
+		},
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 
 		errObj, ok := evaluated.(*object.Error)
 		if !ok {
@@ -275,6 +586,556 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedPosition string
+	}{
+		{"5 + true;", "1:3"},
+		{"let a = 1;\n  a + b", "2:7"},
+		{"let f = fn(x) {\n  x - \"a\"\n};\nf(1);", "2:5"},
+		{`len(1)`, "1:4"},
+		{"[1, 2][true]", "1:7"},
+		{"for (x in 5) { x }", "1:11"},
+		{"range(1, 2, 0)", "1:6"},
+		{"let x = 0;\n10 / x", "2:4"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Pos.String() != tt.expectedPosition {
+			t.Errorf("wrong error position. expected=%q, got=%q",
+				tt.expectedPosition, errObj.Pos)
+		}
+	}
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	tests := []struct {
+		input             string
+		expectedTraceback string
+	}{
+		{
+			"let add = fn(a, b) { a + b };\nadd(1, \"a\")",
+			"Error: 1:24: type mismatch: INTEGER + STRING\n\tat add (called at 2:4)",
+		},
+		{
+			`let inner = fn(x) { x + true };
+let outer = fn(x) {
+  inner(x) * 2
+};
+let main = fn() { outer(1) };
+main();`,
+			"Error: 1:23: type mismatch: INTEGER + BOOLEAN\n" +
+				"\tat inner (called at 3:8)\n" +
+				"\tat outer (called at 5:24)\n" +
+				"\tat main (called at 6:5)",
+		},
+		{
+			"fn(x) { -x }(true)",
+			"Error: 1:9: unknown operator: -BOOLEAN\n\tat <anonymous> (called at 1:13)",
+		},
+		{
+			"let f = fn(x) { x };\nf(1, 2)",
+			"Error: 2:2: wrong number of arguments: want=1, got=2",
+		},
+		{
+			"let f = fn(x) { len(x) };\nf(1)",
+			"Error: 1:20: argument to `len` not supported, got INTEGER\n\tat f (called at 2:2)",
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Traceback() != tt.expectedTraceback {
+			t.Errorf("wrong traceback. expected=%q, got=%q", tt.expectedTraceback, errObj.Traceback())
+		}
+	}
+}
+
+func TestTryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { throw "boom"; 1 } catch (e) { 2 }`, 2},
+		{`try { throw "boom" } catch (e) { e["message"] }`, "boom"},
+		{`try { throw "boom" } catch (e) { e["type"] }`, "Error"},
+		{`try { 1 + true } catch (e) { e["message"] }`, "type mismatch: INTEGER + BOOLEAN"},
+		{`try { 1 + true } catch (e) { e["type"] }`, "RuntimeError"},
+		{`try { throw {"type": "ValueError", "message": "bad"} } catch (e) { e["type"] + ": " + e["message"] }`, "ValueError: bad"},
+		{`try { throw "boom" } catch (e) { }`, nil},
+		{`let e = 5; try { throw "x" } catch (e) { 1 }; e`, 5},
+		{`try { throw {"type": "LimitError", "message": "x"} } catch (e) { "caught" }`, "caught"},
+		{`let n = 0; try { try { throw {"type": "LimitError"} } finally { n = 1 } } catch (e) { [n, e["type"]] }`,
+			[]interface{}{1, "LimitError"}},
+		{`let e = 5; try { throw "x" } catch (e) { e = 1 }; e`, 5},
+		{`let f = fn() { let e = 5; try { throw "x" } catch (e) { 1 }; e }; f()`, 5},
+		{`let f = fn() { try { throw "x" } catch (e) { fn() { e["message"] } } }; f()()`, "x"},
+		{`let n = 1; try { throw "x" } catch (e) { let n = 2; n += 1 }; n`, 1},
+		{`let f = fn() { throw "boom" };
+let g = fn() { f() };
+try { g() } catch (e) { e["trace"] }`, []string{"at f (called at 2:17)", "at g (called at 3:8)"}},
+		{`let r = try { 1 } finally { 2 };
+r`, 1},
+		{`let f = fn() { try { return 1 } finally { 5 } };
+f()`, 1},
+		{`let f = fn() { try { return 1 } finally { return 2 } };
+f()`, 2},
+		{`let f = fn() { try { try { return 1 } finally { throw "inner" } } catch (e) { e["message"] } };
+f()`, "inner"},
+		{`let f = fn() { try { try { return 1 } catch (e) { 2 } } finally { throw "outer" } };
+try { f() } catch (e) { e["message"] }`, "outer"},
+		{`let f = fn() { try { throw "a" } catch (e) { return e["message"] } finally { 10 } };
+f()`, "a"},
+		{`try { try { throw "inner" } finally { 1 } } catch (e) { e["message"] }`, "inner"},
+		{`try { try { throw "inner" } catch (e) { throw e } } catch (e) { e["message"] }`, "inner"},
+		{`try { try { throw "inner" } catch (e) { throw "outer" } } catch (e) { e["message"] }`, "outer"},
+		{`let f = fn(n) { if (n == 0) { throw "bottom" } f(n - 1) };
+try { f(3) } catch (e) { len(e["trace"]) }`, 4},
+		{`let f = fn() {
+  try { return 1 } catch (e) { 0 }
+};
+let g = fn() { try { f(); throw "after" } catch (e) { e["message"] } };
+g()`, "after"},
+		{`let c = fn() {
+  let x = 1;
+  let get = try { throw "x" } catch (e) { fn() { x } };
+  get
+};
+c()()`, 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		case []string:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements. want=%d, got=%d", len(expected), len(array.Elements))
+				continue
+			}
+
+			for i, expectedElem := range expected {
+				testStringObject(t, array.Elements[i], expectedElem)
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestUncaughtErrors(t *testing.T) {
+	tests := []struct {
+		input             string
+		expectedTraceback string
+	}{
+		{`throw "boom"`, "Error: 1:1: boom"},
+		{`throw {"type": "ValueError", "message": "bad"}`, "ValueError: 1:1: bad"},
+		{`let f = fn() { throw "boom" };
+f()`, "Error: 1:16: boom\n\tat f (called at 2:2)"},
+		{`try { throw "boom" } finally { 1 }`, "Error: 1:7: boom"},
+		{`try { 1 } finally { throw "finally" }`, "Error: 1:21: finally"},
+		{`let f = fn() { try { return 1 } finally { throw "f" } };
+f()`, "Error: 1:43: f\n\tat f (called at 2:2)"},
+		{`try { throw "a" } catch (e) { 1 + true }`, "Error: 1:33: type mismatch: INTEGER + BOOLEAN"},
+		{`let f = fn() { throw "inner" };
+let g = fn() { try { f() } finally { 1 } };
+g()`, "Error: 1:16: inner\n\tat f (called at 2:23)\n\tat g (called at 3:2)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Traceback() != tt.expectedTraceback {
+			t.Errorf("wrong traceback. expected=%q, got=%q", tt.expectedTraceback, errObj.Traceback())
+		}
+	}
+}
+
+func TestDeepRecursion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(5000)", 5000},
+		{`let counter = fn() {
+			let c = 0;
+			let inc = fn() { c += 1 };
+			let deep = fn(n) { if (n > 0) { deep(n - 1) } else { inc() } };
+			deep(3000);
+			inc();
+			c
+		};
+		counter()`, 2},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input           string
+		limits          evaluator.Limits
+		expectedMessage string
+	}{
+		{"while (true) { }", evaluator.Limits{MaxSteps: 100}, "step limit exceeded: 100"},
+		{"let f = fn(n) { f(n + 1) }; f(0)", evaluator.Limits{MaxCallDepth: 50}, "call depth limit exceeded: 50"},
+		{"let f = fn(n) { f(n + 1) }; f(0)", evaluator.Limits{}, "call depth limit exceeded: 10000"},
+		{`let s = "ab"; while (true) { s = s + s }`, evaluator.Limits{MaxAllocation: 10}, "allocation limit exceeded: 10"},
+		{"[1, 2, 3, 4]", evaluator.Limits{MaxAllocation: 3}, "allocation limit exceeded: 3"},
+		{"while (true) { }", evaluator.Limits{Timeout: 10 * time.Millisecond}, "timeout exceeded"},
+		{"try { while (true) { } } catch (e) { 1 }", evaluator.Limits{MaxSteps: 100}, "step limit exceeded: 100"},
+		{"let f = fn() { try { f() } finally { return 1 } }; f()", evaluator.Limits{MaxCallDepth: 5},
+			"call depth limit exceeded: 5"},
+		{"let f = fn(x) { map([x], f) }; f(1)", evaluator.Limits{MaxCallDepth: 5}, "call depth limit exceeded: 5"},
+		// sizes of values below are checked before they are allocated
+		{`strings.repeat("x", 500000000)`, evaluator.Limits{MaxAllocation: 1000}, "allocation limit exceeded: 1000"},
+		{"2 ** 100000000", evaluator.Limits{MaxAllocation: 1000}, "allocation limit exceeded: 1000"},
+		{"pow(3, 100000000)", evaluator.Limits{MaxAllocation: 1000}, "allocation limit exceeded: 1000"},
+		{"let x = 2; x *= 2 ** 70; x *= x; x *= x; x *= x; x *= x; x *= x; x *= x; x *= x", evaluator.Limits{MaxAllocation: 1000}, "allocation limit exceeded: 1000"},
+		{`let s = strings.repeat("x", 600); s + s`, evaluator.Limits{MaxAllocation: 1000},
+			"allocation limit exceeded: 1000"},
+		{"reverse(range(1000000000000))", evaluator.Limits{MaxAllocation: 1000}, "allocation limit exceeded: 1000"},
+		{"map(range(1000000000000), fn(x) { x })", evaluator.Limits{MaxAllocation: 1000},
+			"allocation limit exceeded: 1000"},
+		{"filter(range(1000000000000), fn(x) { true })", evaluator.Limits{MaxAllocation: 1000},
+			"allocation limit exceeded: 1000"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := evaluator.EvalContext(context.Background(), program, object.NewEnvironment(), tt.limits)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Kind != "LimitError" || errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expectedMessage, errObj.Inspect())
+		}
+	}
+}
+
+func TestLimitsAreNotExceeded(t *testing.T) {
+	limits := evaluator.Limits{MaxSteps: 1000, MaxCallDepth: 21, MaxAllocation: 10, Timeout: time.Second}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = fn(n) { if (n == 0) { "done" } else { f(n - 1) } }; f(20)`, "done"},
+		// errors thrown by program are caught even if they have type of limit errors
+		{`try { throw {"type": "LimitError", "message": "x"} } catch (e) { "caught" }`, "caught"},
+		{`try { throw {"type": "LimitError", "message": "x"} } catch (e) { e["message"] }`, "x"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		evaluated := evaluator.EvalContext(context.Background(), program, object.NewEnvironment(), limits)
+		testStringObject(t, evaluated, tt.expected)
+		testVM(t, tt.input, program, evaluated)
+	}
+}
+
+func TestEvalContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	env := object.NewEnvironment()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	program := parser.New(lexer.New("while (true) { }")).ParseProgram()
+	evaluated := evaluator.EvalContext(ctx, program, env, evaluator.Limits{})
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Kind != "LimitError" || errObj.Message != "execution canceled" {
+		t.Fatalf("wrong result. got=%s", inspect(evaluated))
+	}
+
+	// limits of finished execution do not apply to the next evaluation in the same environment
+	program = parser.New(lexer.New("1 + 1")).ParseProgram()
+	testIntegerObject(t, evaluator.Eval(program, env), 2)
+}
+
+func TestModules(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"util.puki": `let helper = import "./helper";
+let hidden = 1;
+export let double = fn(x) { helper.twice(x) };
+export let name = "util";`,
+		"helper.puki":  `export let twice = fn(x) { x * 2 };`,
+		"counter.puki": `let count = 0; export let inc = fn() { count += 1; count };`,
+		"live.puki":    `export let count = 0; export let inc = fn() { count += 1 };`,
+		"failing.puki": `export let f = fn() { 1 };
+throw "boom";`,
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let u = import "util"; u.double(21)`, "42"},
+		{`let u = import "util"; u["name"]`, "util"},
+		{`import "util"`, "module util"},
+		{`(import "helper").twice(2)`, "4"},
+		{`let c = import "counter"; c.inc(); let d = import "counter"; d.inc()`, "2"},
+		{`let load = fn() { import "counter" }; load().inc(); load().inc()`, "2"},
+		{`let l = import "live"; let before = l.count; l.inc(); l.inc(); [before, l.count]`, "[0, 2]"},
+		{`let u = import "util"; u.hidden`, "Error: 1:25: module util has no exported binding: hidden"},
+		{`let u = import "util"; u[1]`, "Error: 1:25: index of module must be STRING, got INTEGER"},
+		{`import "failing"`,
+			"Error: " + filepath.Join(dir, "failing.puki") + ":2:1: boom\n\tat module failing (called at 1:1)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if inspect(evaluated) != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, inspect(evaluated))
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"a.puki":      `import "b"`,
+		"b.puki":      `import "a"`,
+		"broken.puki": `let x = ;`,
+	})
+
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`import "missing"`, "module not found: missing"},
+		{`import "./util"`, "module not found: ./util"},
+		{`import "a"`, "import cycle: a"},
+		{`import "broken"`, "syntax error in module: " + filepath.Join(dir, "broken.puki") + ":1:9"},
+	}
+
+	for _, tt := range tests {
+		// the vm backend raises errors of modules which can not be imported when the import is executed too
+		errObj, ok := testEval(t, tt.input).(*object.Error)
+		if !ok || !strings.HasPrefix(errObj.Message, tt.expectedMessage) {
+			t.Errorf("wrong error for %q. expected=%q, got=%+v", tt.input, tt.expectedMessage, errObj)
+		}
+	}
+
+	caught := []struct {
+		input    string
+		expected string
+	}{
+		{`if (false) { import "./missing" }; 1`, "1"},
+		{`try { import "./missing" } catch (e) { "caught " + e["type"] }`, "caught RuntimeError"},
+		{`let load = fn() { import "a" }; try { load() } catch (e) { e["message"] }`, "import cycle: a"},
+	}
+
+	for _, tt := range caught {
+		if evaluated := testEval(t, tt.input); inspect(evaluated) != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, inspect(evaluated))
+		}
+	}
+}
+
+// writeModules writes module files into temporary directory which is used as search path of modules
+func writeModules(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "modules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+		os.Unsetenv("PUKIPATH")
+	})
+
+	for name, source := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Setenv("PUKIPATH", dir); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"while (false) { 1 }", nil},
+		{"let i = 0; let s = 0; while (i < 5) { let s = s + i; let i = i + 1; } s", 10},
+		{"let i = 0; while (true) { if (i == 3) { break } let i = i + 1; } i", 3},
+		{"let s = 0; for (x in [1, 2, 3]) { let s = s + x } s", 6},
+		{"let s = 0; for (i, x in [10, 20]) { let s = s + i * x } s", 20},
+		{`let n = 0; for (c in "héllo") { let n = n + 1 } n`, 5},
+		{`let r = ""; for (c in "abc") { let r = c + r } r`, "cba"},
+		{`let r = ""; for (i, c in "ab") { let r = r + c + c } r`, "aabb"},
+		{`let r = ""; for (k in {"b": 1, "a": 2}) { let r = r + k } r`, "ba"},
+		{`let s = 0; for (k, v in {"a": 1, "b": 2}) { let s = s + v } s`, 3},
+		{"let s = 0; for (x in range(5)) { let s = s + x } s", 10},
+		{"let s = 0; for (x in range(1, 10, 3)) { let s = s + x } s", 12},
+		{"let s = 0; for (x in range(5, 0, -1)) { let s = s + x } s", 15},
+		{"let s = 0; for (i, x in range(5, 8)) { let s = s + i } s", 3},
+		{"let s = 0; for (x in range(10)) { if (x > 2) { continue } let s = s + x } s", 3},
+		{`let s = 0;
+for (x in range(3)) {
+  for (y in range(3)) {
+    if (y == 1) { break }
+    let s = s + 1
+  }
+}
+s`, 3},
+		{"let f = fn() { for (x in range(100)) { if (x == 7) { return x } } }; f()", 7},
+		{"let s = 0; for (x in range(100000)) { let s = s + x } s", 4999950000},
+		{"let n = 0; for (x in range(3)) { try { break } finally { let n = n + 10 } } n", 10},
+		{`let n = 0;
+for (x in range(3)) {
+  try {
+    if (x == 1) { continue }
+    throw "e"
+  } catch (e) {
+    n += 1
+  }
+}
+n`, 2},
+		{`let n = 0;
+for (x in range(3)) {
+  try {
+    try { continue } finally { let n = n + 1 }
+  } catch (e) { n += 100 }
+}
+n`, 3},
+		{`let f = fn() {
+  let fs = [];
+  for (x in range(3)) { let fs = push(fs, fn() { x }) }
+  fs
+};
+f()[0]()`, 2},
+		{`let f = fn() {
+  let i = 0;
+  while (true) {
+    let i = i + 1;
+    if (i < 3) { continue }
+    break
+  }
+  i
+};
+f()`, 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x += 2; x", 3},
+		{"let x = 10; x -= 2; x", 8},
+		{"let x = 3; x *= 4; x", 12},
+		{"let x = 12; x /= 4; x", 3},
+		{"let x = 1; let y = (x = 5); x + y", 10},
+		{"let a = 1; let b = 1; a = b = 7; a + b", 14},
+		{"let x = 1; x = 2", 2},
+		{"let i = 0; while (i < 10) { i += 1 } i", 10},
+		{`let counter = fn() {
+  let c = 0;
+  fn() { c += 1; c }
+};
+let next = counter();
+next(); next(); next()`, 3},
+		{`let counter = fn() {
+  let c = 0;
+  let inc = fn() { c += 1 };
+  inc(); inc();
+  c
+};
+counter()`, 2},
+		{"let total = 0; let add = fn(x) { total += x }; add(2); add(3); total", 5},
+		{"let x = 1; let f = fn() { let x = 5; x = 2 }; f(); x", 1},
+		{"let f = fn() { let x = 1; let g = fn() { fn() { x = 10 } }; g()(); x }; f()", 10},
+		{"let a = [1, 2, 3]; a[0] = 5; a[0] + a[1]", 7},
+		{"let a = [1, 2, 3]; a[1] += 10; a[1]", 12},
+		{"let a = [1]; let b = a; b[0] = 9; a[0]", 9},
+		{`let h = {}; h["k"] = 1; h["k"]`, 1},
+		{`let h = {"k": 1}; h["k"] += 1; h["k"]`, 2},
+		{`let h = {"a": 1}; h[2] = "two"; h[2]`, "two"},
+		{`let h = {"a": [1, 2]}; h["a"][1] = 5; h["a"][1]`, 5},
+		{"let n = 0; let idx = fn() { n += 1; 0 }; let a = [1]; a[idx()] += 5; n * 100 + a[0]", 106},
+		{"let s = 0; for (x in range(4)) { s += x } s", 6},
+		{"let f = fn() { let fs = []; for (x in range(3)) { fs = push(fs, x) } fs }; len(f())", 3},
+		{"let x = 1; let f = fn() { x = fn() { 2 } }; f(); x()", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -287,13 +1148,13 @@ func TestLetStatements(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		testIntegerObject(t, testEval(tt.input), tt.expected)
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
 	}
 }
 
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
-	evaluated := testEval(input)
+	evaluated := testEval(t, input)
 
 	fn, ok := evaluated.(*object.Function)
 	if !ok {
@@ -330,7 +1191,7 @@ func TestFunctionApplication(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		testIntegerObject(t, testEval(tt.input), tt.expected)
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
 	}
 }
 
@@ -344,13 +1205,13 @@ func TestClosures(t *testing.T) {
 	sex(2);
 	`
 
-	testIntegerObject(t, testEval(input), 3)
+	testIntegerObject(t, testEval(t, input), 3)
 }
 
 func TestStringLiteral(t *testing.T) {
 	input := `"tachka sova"`
 
-	evaluated := testEval(input)
+	evaluated := testEval(t, input)
 	str, ok := evaluated.(*object.String)
 	if !ok {
 		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
@@ -364,7 +1225,7 @@ func TestStringLiteral(t *testing.T) {
 func TestStringConcatenation(t *testing.T) {
 	input := `"I love" + " " + "Tan9!"`
 
-	evaluated := testEval(input)
+	evaluated := testEval(t, input)
 	str, ok := evaluated.(*object.String)
 	if !ok {
 		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
@@ -395,31 +1256,293 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`push([1,2,3], true)`, []interface{}{1, 2, 3, true}},
 		{`sum([1,2,3])`, 6},
 		{`sum([1,true])`, "unsupported type to `sum`, got BOOLEAN"},
+		{`len("привет")`, 6},
+		{`first("привет")`, "п"},
+		{`last("привет")`, "т"},
+		{`tail("привет")`, "ривет"},
+		{`bytes("hi")`, []interface{}{104, 105}},
+		{`len(bytes("привет"))`, 12},
+		{`bytes(1)`, "argument to `bytes` must be STRING, got INTEGER"},
+		{`floor(2.7)`, 2.0},
+		{`floor(-2.5)`, -3.0},
+		{`floor(2)`, 2},
+		{`ceil(2.1)`, 3.0},
+		{`round(2.5)`, 3.0},
+		{`round(1234.5678 * 100) / 100`, 1234.57},
+		{`round("1")`, "argument to `round` must be INTEGER or FLOAT, got STRING"},
+		{`sqrt(16)`, 4.0},
+		{`sqrt(2.25)`, 1.5},
+		{`pow(2, 10)`, 1024},
+		{`pow(2, -1)`, 0.5},
+		{`pow(2.0, 3)`, 8.0},
+		{`pow(2)`, "wrong number of arguments. got=1, want=2"},
+		{`abs(-3)`, 3},
+		{`abs(-2.5)`, 2.5},
+		{`min(3, 1.5, 2)`, 1.5},
+		{`max(3, 1.5, 2)`, 3},
+		{`max([1, 5, 2])`, 5},
+		{`min([])`, "argument to `min` must not be empty ARRAY"},
+		{`max(1, "2")`, "arguments to `max` must be INTEGER or FLOAT, got STRING"},
+		{`range()`, "wrong number of arguments. got=0, want=1..3"},
+		{`min()`, "wrong number of arguments. got=0, want=1+"},
+		{`builtins()[0]["name"]`, "abs"},
+		{`builtins()[0]["signature"]`, "abs(INTEGER|FLOAT)"},
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		testBuiltInResult(t, testEval(t, tt.input), tt.expected)
+	}
+}
 
-		switch expected := tt.expected.(type) {
-		case int:
-			testIntegerObject(t, evaluated, int64(expected))
-		case []interface{}:
-			testArrayObject(t, evaluated, expected)
-		case string:
-			if errObj, ok := evaluated.(*object.Error); ok {
-				if errObj.Message != expected {
-					t.Errorf("wrong error message. expected=%q, got=%q",
-						expected, errObj.Message)
-				}
-			} else {
-				testStringObject(t, evaluated, string(expected))
+func TestStringFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`strings.split("a,b,,c", ",")`, []interface{}{"a", "b", "", "c"}},
+		{`strings.split("  a b   c ")`, []interface{}{"a", "b", "c"}},
+		{`strings.split("héllo", "")`, []interface{}{"h", "é", "l", "l", "o"}},
+		{`strings.join(["a", "b", "c"], ", ")`, "a, b, c"},
+		{`strings.join(["a", "b"])`, "ab"},
+		{`strings.join([])`, ""},
+		{`strings.join(["a", 1])`, "elements of array to `strings.join` must be STRING, got INTEGER"},
+		{`strings.trim("  hi  ")`, "hi"},
+		{`strings.trim("xxhixx", "x")`, "hi"},
+		{`strings.trimLeft("  hi  ")`, "hi  "},
+		{`strings.trimRight("  hi  ")`, "  hi"},
+		{`strings.trimRight("hi!?!", "!?")`, "hi"},
+		{`strings.upper("héllo")`, "HÉLLO"},
+		{`strings.lower("HeLLo")`, "hello"},
+		{`strings.contains("hello", "ell")`, true},
+		{`strings.contains("hello", "xyz")`, false},
+		{`strings.startsWith("hello", "he")`, true},
+		{`strings.endsWith("hello", "he")`, false},
+		{`strings.indexOf("привет", "вет")`, 3},
+		{`strings.indexOf("hello", "xyz")`, -1},
+		{`strings.replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`strings.repeat("ab", 3)`, "ababab"},
+		{`strings.repeat("ab", 0)`, ""},
+		{`strings.repeat("ab", -1)`, "count of `strings.repeat` must not be negative, got -1"},
+		{`strings.repeat("ab", 9223372036854775807)`, "result of `strings.repeat` is too long"},
+		{`strings.substring("привет", 1, 3)`, "ри"},
+		{`strings.substring("hello", 2)`, "llo"},
+		{`strings.substring("hello", 2, 10)`, "indexes of `strings.substring` out of range: [2:10] with length 5"},
+		{`strings.slice("hello", -3)`, "llo"},
+		{`strings.slice("hello", 1, -1)`, "ell"},
+		{`strings.slice("hello", 3, 1)`, ""},
+		{`strings.slice("hello", -10, 10)`, "hello"},
+		{`strings.format("%s is %d years, %v%%", "Ann", 30, [1.5, true])`, "Ann is 30 years, [1.5, true]%"},
+		{`strings.format("%d", "1")`, "%d in `strings.format` requires INTEGER, got STRING"},
+		{`strings.format("%s", 1)`, "%s in `strings.format` requires STRING, got INTEGER"},
+		{`strings.format("%s %s", "a")`, "missing argument for %s in `strings.format`"},
+		{`strings.format("%s", "a", "b")`, "too many arguments to `strings.format`: got=2, want=1"},
+		{`strings.format("%x", 1)`, "unknown verb %x in `strings.format`"},
+		{`strings.format("100%")`, "format of `strings.format` ends with %"},
+		{`strings.upper(1)`, "argument 1 to `strings.upper` must be STRING, got INTEGER"},
+		{`strings.contains("a")`, "wrong number of arguments. got=1, want=2"},
+		{`strings.substring("hello", "1")`, "argument 2 to `strings.substring` must be INTEGER, got STRING"},
+	}
+
+	for _, tt := range tests {
+		testBuiltInResult(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestArrayFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, []interface{}{2, 4, 6}},
+		{`map(range(3), fn(x) { x + 1 })`, []interface{}{1, 2, 3}},
+		{`map(range(9223372036854775800, 9223372036854775807, 10), fn(x) { x })`,
+			[]interface{}{9223372036854775800}},
+		{`map(range(9223372036854775805, 9223372036854775807), fn(x) { x })`,
+			[]interface{}{9223372036854775805, 9223372036854775806}},
+		{`map(range(-9223372036854775800, -9223372036854775807 - 1, -10), fn(x) { x })`,
+			[]interface{}{-9223372036854775800}},
+		{`reverse(range(-9223372036854775807 - 1, -9223372036854775807 + 1))`,
+			[]interface{}{-9223372036854775807, -9223372036854775807 - 1}},
+		{`map([], fn(x) { x })`, []interface{}{}},
+		{`map([1], len)`, "argument to `len` not supported, got INTEGER"},
+		{`map(1, fn(x) { x })`, "argument 1 to `map` must be ARRAY|RANGE, got INTEGER"},
+		{`map([1], fn(x, y) { x })`, "wrong number of arguments: want=2, got=1"},
+		{`filter([1, 2, 3, 4], fn(x) { x % 2 == 0 })`, []interface{}{2, 4}},
+		{`reduce([1, 2, 3], fn(acc, x) { acc + x })`, 6},
+		{`reduce([1, 2, 3], fn(acc, x) { acc + x }, 10)`, 16},
+		{`reduce([], fn(acc, x) { acc + x })`, "`reduce` of empty ARRAY without initial value"},
+		{`let s = 0; each([1, 2, 3], fn(x) { s += x }); s`, 6},
+		{`find([1, 2, 3], fn(x) { x > 1 })`, 2},
+		{`if (find([1, 2, 3], fn(x) { x > 5 })) { 1 } else { 2 }`, 2},
+		{`any([1, 2, 3], fn(x) { x > 2 })`, true},
+		{`any([], fn(x) { true })`, false},
+		{`any([false, 0])`, true},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, true},
+		{`all([true, false])`, false},
+		{`all([])`, true},
+		{`sort([3, 1, 2])`, []interface{}{1, 2, 3}},
+		{`sort(["b", "c", "a"])`, []interface{}{"a", "b", "c"}},
+		{`sort([3, 1, 2], fn(a, b) { a > b })`, []interface{}{3, 2, 1}},
+		{`sort([3, 1, 2], fn(a, b) { b - a })`, []interface{}{3, 2, 1}},
+		{`sort([3, 1, 2], fn(a, b) { a - b })`, []interface{}{1, 2, 3}},
+		{`sort([2, 1], fn(a, b) { "less" })`, "comparator of `sort` must return BOOLEAN or INTEGER, got STRING"},
+		{`sort([2, 1], fn(a, b) { if (false) { 1 } })`, "comparator of `sort` must return BOOLEAN or INTEGER, got NULL"},
+		{`sort([1, "a"])`, "cannot compare STRING with INTEGER"},
+		{`sort([[2, 1], [1, 2], [1]])`, []interface{}{[]interface{}{1}, []interface{}{1, 2}, []interface{}{2, 1}}},
+		{`uniq([[1, 2], [1, 2], [2, 1]])`, []interface{}{[]interface{}{1, 2}, []interface{}{2, 1}}},
+		{`len(uniq([{"a": 1}, {"a": 1}, {"a": 2}]))`, 2},
+		{`sortBy(["ccc", "a", "bb"], len)`, []interface{}{"a", "bb", "ccc"}},
+		{`sortBy([[2, "b"], [1, "a"]], first)[0][1]`, "a"},
+		{`reverse([1, 2, 3])`, []interface{}{3, 2, 1}},
+		{`reverse(range(3))`, []interface{}{2, 1, 0}},
+		{`zip([1, 2, 3], ["a", "b"])[1]`, []interface{}{2, "b"}},
+		{`len(zip([1, 2, 3], ["a", "b"]))`, 2},
+		{`flatten([1, [2, [3]], [], 4])[2]`, []interface{}{3}},
+		{`flatten([1, [2, [3]], [], 4], 2)`, []interface{}{1, 2, 3, 4}},
+		{`flatten([1], -1)`, "depth of `flatten` must not be negative, got -1"},
+		{`let a = [1, 2]; a[0] = a; flatten(a, 1000000)`, "cannot flatten array which contains itself"},
+		{`let a = [1, 2]; a[0] = a; len(flatten(a))`, 3},
+		{`uniq([1, 2, 1, 3, 2, 1.0])`, []interface{}{1, 2, 3}},
+		{`uniq(["a", "b", "a"])`, []interface{}{"a", "b"}},
+		{`slice([1, 2, 3, 4], 1, 3)`, []interface{}{2, 3}},
+		{`slice([1, 2, 3, 4], -2)`, []interface{}{3, 4}},
+		{`slice([1, 2, 3, 4], 3, 1)`, []interface{}{}},
+		{`len(map(range(100000), fn(x) { x }))`, 100000},
+		{`try { map([1], fn(x) { throw "boom" }) } catch (e) { e["message"] }`, "boom"},
+		{`map([1, 2], fn(x) { try { throw "a" } catch (e) { x } })`, []interface{}{1, 2}},
+		{`map([[1], [2]], fn(x) { map(x, fn(y) { y * 10 })[0] })`, []interface{}{10, 20}},
+	}
+
+	for _, tt := range tests {
+		testBuiltInResult(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestHashFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`keys({"b": 1, "a": 2})`, []interface{}{"b", "a"}},
+		{`values({"b": 1, "a": 2})`, []interface{}{1, 2}},
+		{`entries({"a": 1})`, []interface{}{[]interface{}{"a", 1}}},
+		{`keys({})`, []interface{}{}},
+		{`keys([1])`, "argument 1 to `keys` must be HASH, got ARRAY"},
+		{`len({"a": 1, "b": 2})`, 2},
+		{`has({"a": 1}, "a")`, true},
+		{`has({"a": 1}, "b")`, false},
+		{`has({1: 1}, 1.0)`, true},
+		{`has({"a": 1}, fn() { 1 })`, "unusable as hash key: FUNCTION"},
+		{`has({[1, 2]: 1}, [1, 2])`, true},
+		{`let h = {"a": 1}; let g = set(h, "b", 2); [len(h), g["b"]]`, []interface{}{1, 2}},
+		{`set({"a": 1}, "a", 2)["a"]`, 2},
+		{`let h = {"a": 1, "b": 2}; let g = delete(h, "a"); [len(h), len(g), has(g, "a")]`,
+			[]interface{}{2, 1, false}},
+		{`len(delete({"a": 1}, "missing"))`, 1},
+		{`let m = merge({"a": 1, "b": 1}, {"b": 2}, {"c": 3}); [m["a"], m["b"], m["c"]]`, []interface{}{1, 2, 3}},
+		{`merge({"a": 1}, 1)`, "argument 2 to `merge` must be HASH, got INTEGER"},
+		{`fromEntries([["a", 1], ["b", 2]])["b"]`, 2},
+		{`len(fromEntries(entries({"a": 1, "b": 2})))`, 2},
+		{`fromEntries([["a"]])`, "elements of array to `fromEntries` must be [key, value] pairs, got [a]"},
+		{`fromEntries([[[len], 1]])`, "unusable as hash key: ARRAY"},
+		{`fromEntries([[[1], 1]])[[1]]`, 1},
+		{`fromEntries(map(["x", "y"], fn(k) { [k, strings.upper(k)] }))["y"]`, "Y"},
+	}
+
+	for _, tt := range tests {
+		testBuiltInResult(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestArrayFunctionErrorTrace(t *testing.T) {
+	input := `let check = fn(x) { if (x > 1) { throw "too big" } x };
+map([1, 2], check)`
+
+	evaluated := testEval(t, input)
+
+	expected := "Error: 1:34: too big\n\tat check (called at 2:4)"
+	if inspect(evaluated) != expected {
+		t.Errorf("wrong traceback. expected=%q, got=%q", expected, inspect(evaluated))
+	}
+}
+
+// testBuiltInResult checks result of built in function, expected string is message of error if result is error
+func testBuiltInResult(t *testing.T, evaluated object.Object, expected interface{}) {
+	t.Helper()
+
+	switch expected := expected.(type) {
+	case int:
+		testIntegerObject(t, evaluated, int64(expected))
+	case float64:
+		testFloatObject(t, evaluated, expected)
+	case bool:
+		testBooleanObject(t, evaluated, expected)
+	case []interface{}:
+		testArrayObject(t, evaluated, expected)
+	case string:
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					expected, errObj.Message)
 			}
+		} else {
+			testStringObject(t, evaluated, string(expected))
+		}
+	}
+}
+
+func TestBuiltInRegistry(t *testing.T) {
+	registry := evaluator.NewBuiltIns()
+	registry.Remove("len")
+
+	err := registry.Register(&object.BuiltIn{
+		Name:   "text.twice",
+		Params: []string{"STRING"},
+		Fn: func(args ...object.Object) object.Object {
+			str := args[0].(*object.String).Value
+			return &object.String{Value: str + str}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`text.twice("ab")`, "abab"},
+		{`let twice = text.twice; twice("c")`, "cc"},
+		{`text.twice()`, "Error: 1:11: wrong number of arguments. got=0, want=1"},
+		{`text.upper("a")`, "Error: 1:5: module text has no exported binding: upper"},
+		{`len("a")`, "Error: 1:1: identifier not found: len"},
+		{`find(builtins(), fn(b) { b["name"] == "text.twice" })["signature"]`, "text.twice(STRING)"},
+		{`let text = "shadowed"; text`, "shadowed"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.SetBuiltIns(registry)
+
+		evaluated := evaluator.Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+		if inspect(evaluated) != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, inspect(evaluated))
 		}
 	}
+
+	// environments without own registry use the standard built in functions
+	testIntegerObject(t, testEval(t, `len("a")`), 1)
 }
 
 func testArrayObject(t *testing.T, array object.Object, expected []interface{}) {
-	arr := array.(*object.Array)
+	arr, ok := array.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%s", inspect(array))
+	}
+
+	if len(arr.Elements) != len(expected) {
+		t.Fatalf("wrong number of elements. expected=%d, got=%d", len(expected), len(arr.Elements))
+	}
 
 	for i, el := range arr.Elements {
 		switch el.Type() {
@@ -439,6 +1562,12 @@ func testArrayObject(t *testing.T, array object.Object, expected []interface{})
 					expected[i], el)
 			}
 
+		case object.StringObj:
+			testStringObject(t, el, expected[i].(string))
+
+		case object.ArrayObj:
+			testArrayObject(t, el, expected[i].([]interface{}))
+
 		default:
 			t.Fatalf("unsupported type. got=%T", el)
 		}
@@ -448,7 +1577,7 @@ func testArrayObject(t *testing.T, array object.Object, expected []interface{})
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
-	evaluated := testEval(input)
+	evaluated := testEval(t, input)
 	result, ok := evaluated.(*object.Array)
 	if !ok {
 		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
@@ -513,10 +1642,22 @@ func TestIndexExpressions(t *testing.T) {
 			`"0123"[0]`,
 			"0",
 		},
+		{
+			`"0123"[4]`,
+			nil,
+		},
+		{
+			`"привет"[0]`,
+			"п",
+		},
+		{
+			`"日本語"[2]`,
+			"語",
+		},
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 
 		switch obj := tt.expected.(type) {
 		case int:
@@ -529,6 +1670,58 @@ func TestIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestHashOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, 3: 3, true: 4}`, "{b:1, a:2, 3:3, true:4}"},
+		{`let h = {"b": 1, "a": 2}; h["c"] = 3; h["b"] = 4; h`, "{b:4, a:2, c:3}"},
+		{`{"a": 1, "b": 2, "a": 3}`, "{a:3, b:2}"},
+		{`set({"b": 1, "a": 2}, "b", 3)`, "{b:3, a:2}"},
+		{`set(delete({"b": 1, "a": 2}, "b"), "b", 3)`, "{a:2, b:3}"},
+		{`merge({"b": 1, "a": 2}, {"c": 3, "b": 4})`, "{b:4, a:2, c:3}"},
+		{`entries({"z": 1, "y": 2})`, "[[z, 1], [y, 2]]"},
+		{`fromEntries([["z", 1], ["y", 2]])`, "{z:1, y:2}"},
+		{`let r = []; let h = {"z": 1, "y": 2, "x": 3}; for (k, v in h) { let r = push(r, k) } r`, "[z, y, x]"},
+		{`let log = []; let f = fn(x) { log = push(log, x); x };
+		{f("b"): f(1), f("a"): f(2)}; log`, "[b, 1, a, 2]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, inspect(evaluated))
+		}
+	}
+}
+
+func TestHashKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let grid = {[0, 0]: "start", [1, 2]: "end"}; grid[[1, 2]]`, "end"},
+		{`{[0, 0]: 1}[[0, 1]]`, "null"},
+		{`{[1, [2, 3]]: 1}[[1, [2, 3]]]`, "1"},
+		{`{[1]: 1}[[1.0]]`, "1"},
+		{`{[]: 1}[[]]`, "1"},
+		{`let none = if (false) { 1 }; {none: 1}[none]`, "1"},
+		{`let h = {}; h[[1, 2]] = 1; h[[1, 2]] += 1; h`, "{[1, 2]:2}"},
+		{`let k = [1, 2]; let h = {k: 1}; k[0] = 5; [h[[1, 2]], h[k]]`, "[1, null]"},
+		{`{[1, 2]: 1} == {[1, 2]: 1}`, "true"},
+		{`let none = if (false) { 1 }; keys({[1, 2]: 1, none: 2})`, "[[1, 2], null]"},
+		{`let none = if (false) { 1 }; uniq([[1, 2], [1, 2], none, none])`, "[[1, 2], null]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, inspect(evaluated))
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `
 	let two = "two";
@@ -541,27 +1734,27 @@ func TestHashLiterals(t *testing.T) {
 		false: 6 
 	}`
 
-	evaluated := testEval(input)
+	evaluated := testEval(t, input)
 	result, ok := evaluated.(*object.Hash)
 	if !ok {
 		t.Fatalf("Eval don't return Hash. gpt=%T (%+v)", evaluated, evaluated)
 	}
 
-	expected := map[object.HashKey]int64{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Integer{Value: 4}).HashKey():      4,
-		evaluator.TRUE.HashKey():                   5,
-		evaluator.FALSE.HashKey():                  6,
+	expected := map[object.Object]int64{
+		&object.String{Value: "one"}:   1,
+		&object.String{Value: "two"}:   2,
+		&object.String{Value: "three"}: 3,
+		&object.Integer{Value: 4}:      4,
+		evaluator.TRUE:                 5,
+		evaluator.FALSE:                6,
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 
 	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+		pair, ok := result.Get(expectedKey)
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
 		}
@@ -603,10 +1796,34 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{false: 5}[false]`,
 			5,
 		},
+		{
+			`{1.5: 5}[1.5]`,
+			5,
+		},
+		{
+			`{1: 5}[1.0]`,
+			5,
+		},
+		{
+			`{2.0: 5}[2]`,
+			5,
+		},
+		{
+			`{1.5: 5}[1]`,
+			nil,
+		},
+		{
+			`let h = {}; h[1e19] = 5; h[10 ** 19]`,
+			5,
+		},
+		{
+			`{10 ** 19: 5}[1e19]`,
+			5,
+		},
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		integer, ok := tt.expected.(int)
 		if ok {
 			testIntegerObject(t, evaluated, int64(integer))
@@ -0,0 +1,114 @@
+package evaluator
+
+import (
+	"github.com/ythosa/pukiclang/src/ast"
+	"github.com/ythosa/pukiclang/src/object"
+)
+
+// Keys of hash which represents caught error
+const (
+	errorMessageKey = "message"
+	errorTypeKey    = "type"
+	errorTraceKey   = "trace"
+)
+
+// Types of errors
+const (
+	runtimeErrorType = "RuntimeError" // error raised by interpreter
+	thrownErrorType  = "Error"        // error thrown by program without explicit type
+	limitErrorType   = "LimitError"   // error raised when execution exceeds its limits, it can not be caught
+)
+
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(te.Block, env)
+	if isLimitError(result) {
+		// neither catch nor finally block may prolong execution which has to be stopped
+		return result
+	}
+
+	if errObj, ok := result.(*object.Error); ok && te.Catch != nil {
+		// catch parameter and variables defined in catch block do not override variables of the enclosing scope
+		catchEnv := object.NewEnclosedEnvironment(env)
+		catchEnv.Set(te.CatchParam.Value, errorToHash(errObj))
+		result = Eval(te.Catch, catchEnv)
+		if isLimitError(result) {
+			return result
+		}
+	}
+
+	if te.Finally != nil {
+		// error, return, break or continue in finally block overrides result of try and catch blocks
+		finally := Eval(te.Finally, env)
+		if isError(finally) || isReturnValue(finally) || finally == BREAK || finally == CONTINUE {
+			return finally
+		}
+	}
+
+	if result == nil {
+		return NULL
+	}
+
+	return result
+}
+
+// throwValue returns error which is thrown by throw statement with passed value.
+// Strings become messages of errors, hashes may set message and type of error
+func throwValue(value object.Object) *object.Error {
+	switch value := value.(type) {
+	case *object.String:
+		return &object.Error{Message: value.Value, Kind: thrownErrorType}
+
+	case *object.Hash:
+		errObj := &object.Error{Message: value.Inspect(), Kind: thrownErrorType}
+
+		if message, ok := hashStringValue(value, errorMessageKey); ok {
+			errObj.Message = message
+		}
+		if kind, ok := hashStringValue(value, errorTypeKey); ok && kind != "" {
+			errObj.Kind = kind
+		}
+
+		return errObj
+
+	default:
+		return &object.Error{Message: value.Inspect(), Kind: thrownErrorType}
+	}
+}
+
+// errorToHash returns hash with message, type and stack trace of caught error
+func errorToHash(errObj *object.Error) *object.Hash {
+	kind := errObj.Kind
+	if kind == "" {
+		kind = runtimeErrorType
+	}
+
+	trace := make([]object.Object, len(errObj.Stack))
+	for i, frame := range errObj.Stack {
+		trace[i] = &object.String{Value: frame.String()}
+	}
+
+	hash := object.NewHash()
+	setHashValue(hash, errorMessageKey, &object.String{Value: errObj.Message})
+	setHashValue(hash, errorTypeKey, &object.String{Value: kind})
+	setHashValue(hash, errorTraceKey, &object.Array{Elements: trace})
+
+	return hash
+}
+
+func hashStringValue(hash *object.Hash, key string) (string, bool) {
+	pair, ok := hash.Get(&object.String{Value: key})
+	if !ok {
+		return "", false
+	}
+
+	str, ok := pair.Value.(*object.String)
+	if !ok {
+		return "", false
+	}
+
+	return str.Value, true
+}
+
+func setHashValue(hash *object.Hash, key string, value object.Object) {
+	hash.Set(&object.String{Value: key}, value)
+}
@@ -0,0 +1,177 @@
+package evaluator
+
+import (
+	"github.com/ythosa/pukiclang/src/object"
+)
+
+// hashBuiltIns returns built in functions which work with pairs of hashes
+func hashBuiltIns() []*object.BuiltIn {
+	return []*object.BuiltIn{
+		{Name: "keys", Fn: keys, Params: []string{"HASH"},
+			Doc: "returns array of keys of hash"},
+		{Name: "values", Fn: values, Params: []string{"HASH"},
+			Doc: "returns array of values of hash"},
+		{Name: "entries", Fn: entries, Params: []string{"HASH"},
+			Doc: "returns array of [key, value] pairs of hash"},
+		{Name: "fromEntries", Fn: fromEntries, Params: []string{"ARRAY"},
+			Doc: "returns hash with [key, value] pairs of array, later pairs override earlier ones"},
+		{Name: "has", Fn: has, Params: []string{"HASH", "ANY"},
+			Doc: "returns true if hash contains key"},
+		{Name: "set", Fn: set, Params: []string{"HASH", "ANY", "ANY"},
+			Doc: "returns new hash with pairs of hash and value set by key"},
+		{Name: "delete", Fn: deleteBuiltIn, Params: []string{"HASH", "ANY"},
+			Doc: "returns new hash with pairs of hash except pair with key"},
+		{Name: "merge", Fn: merge, Params: []string{"HASH"}, Variadic: true,
+			Doc: "returns new hash with pairs of all hashes, pairs of later hashes override earlier ones"},
+	}
+}
+
+// hashOf returns hash argument with index i of built in function
+func hashOf(name string, i int, arg object.Object) (*object.Hash, *object.Error) {
+	hash, ok := arg.(*object.Hash)
+	if !ok {
+		return nil, argumentError(name, i, object.HashObj, arg)
+	}
+
+	return hash, nil
+}
+
+// checkHashKey returns error if object cannot be key of hash
+func checkHashKey(key object.Object) *object.Error {
+	if _, ok := object.HashKeyOf(key); !ok {
+		return newError("unusable as hash key: %s", key.Type())
+	}
+
+	return nil
+}
+
+// copyHash returns new hash with the same pairs as hash
+func copyHash(hash *object.Hash) *object.Hash {
+	result := object.NewHash()
+	for _, pair := range hash.Pairs() {
+		result.Set(pair.Key, pair.Value)
+	}
+
+	return result
+}
+
+func keys(args ...object.Object) object.Object {
+	return mapPairs("keys", args[0], func(pair object.HashPair) object.Object {
+		return pair.Key
+	})
+}
+
+func values(args ...object.Object) object.Object {
+	return mapPairs("values", args[0], func(pair object.HashPair) object.Object {
+		return pair.Value
+	})
+}
+
+func entries(args ...object.Object) object.Object {
+	return mapPairs("entries", args[0], func(pair object.HashPair) object.Object {
+		return &object.Array{Elements: []object.Object{pair.Key, pair.Value}}
+	})
+}
+
+// mapPairs returns array of results of fn called with every pair of hash argument in iteration order
+func mapPairs(name string, arg object.Object, fn func(pair object.HashPair) object.Object) object.Object {
+	hash, errObj := hashOf(name, 0, arg)
+	if errObj != nil {
+		return errObj
+	}
+
+	pairs := hash.Pairs()
+
+	elements := make([]object.Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = fn(pair)
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+func fromEntries(args ...object.Object) object.Object {
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return argumentError("fromEntries", 0, object.ArrayObj, args[0])
+	}
+
+	hash := object.NewHash()
+	for _, element := range arr.Elements {
+		entry, ok := element.(*object.Array)
+		if !ok || len(entry.Elements) != 2 {
+			return newError("elements of array to `fromEntries` must be [key, value] pairs, got %s",
+				element.Inspect())
+		}
+
+		if !hash.Set(entry.Elements[0], entry.Elements[1]) {
+			return newError("unusable as hash key: %s", entry.Elements[0].Type())
+		}
+	}
+
+	return hash
+}
+
+func has(args ...object.Object) object.Object {
+	hash, errObj := hashOf("has", 0, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	if errObj := checkHashKey(args[1]); errObj != nil {
+		return errObj
+	}
+
+	_, ok := hash.Get(args[1])
+
+	return nativeBoolToBooleanObject(ok)
+}
+
+func set(args ...object.Object) object.Object {
+	hash, errObj := hashOf("set", 0, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	if errObj := checkHashKey(args[1]); errObj != nil {
+		return errObj
+	}
+
+	result := copyHash(hash)
+	result.Set(args[1], args[2])
+
+	return result
+}
+
+func deleteBuiltIn(args ...object.Object) object.Object {
+	hash, errObj := hashOf("delete", 0, args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	if errObj := checkHashKey(args[1]); errObj != nil {
+		return errObj
+	}
+
+	result := copyHash(hash)
+	result.Delete(args[1])
+
+	return result
+}
+
+func merge(args ...object.Object) object.Object {
+	result := object.NewHash()
+
+	for i, arg := range args {
+		hash, errObj := hashOf("merge", i, arg)
+		if errObj != nil {
+			return errObj
+		}
+
+		for _, pair := range hash.Pairs() {
+			result.Set(pair.Key, pair.Value)
+		}
+	}
+
+	return result
+}
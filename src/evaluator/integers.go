@@ -0,0 +1,158 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/ythosa/pukiclang/src/object"
+)
+
+// evalInfixIntegerExpression evaluates infix expression with integer or big integer operands,
+// result is promoted to big integer on overflow and demoted back to integer when it fits
+func evalInfixIntegerExpression(
+	operator string,
+	left object.Object,
+	right object.Object,
+) object.Object {
+	leftInt, leftOk := left.(*object.Integer)
+	rightInt, rightOk := right.(*object.Integer)
+
+	if leftOk && rightOk {
+		if result, ok := evalInfixSmallIntegerExpression(operator, leftInt.Value, rightInt.Value); ok {
+			return result
+		}
+	}
+
+	return evalInfixBigIntegerExpression(operator, toBigInt(left), toBigInt(right))
+}
+
+// evalInfixSmallIntegerExpression evaluates infix expression with int64 operands,
+// it returns false if result overflows int64
+func evalInfixSmallIntegerExpression(operator string, leftVal, rightVal int64) (object.Object, bool) {
+	switch operator {
+	case "+":
+		result := leftVal + rightVal
+		if (rightVal > 0 && result < leftVal) || (rightVal < 0 && result > leftVal) {
+			return nil, false
+		}
+		return &object.Integer{Value: result}, true
+	case "-":
+		result := leftVal - rightVal
+		if (rightVal > 0 && result > leftVal) || (rightVal < 0 && result < leftVal) {
+			return nil, false
+		}
+		return &object.Integer{Value: result}, true
+	case "*":
+		if leftVal == 0 || rightVal == 0 {
+			return &object.Integer{Value: 0}, true
+		}
+		result := leftVal * rightVal
+		if result/rightVal != leftVal ||
+			(leftVal == -1 && rightVal == math.MinInt64) || (rightVal == -1 && leftVal == math.MinInt64) {
+			return nil, false
+		}
+		return &object.Integer{Value: result}, true
+	case "/":
+		if rightVal == 0 {
+			return newError("division by zero"), true
+		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			return nil, false
+		}
+		return &object.Integer{Value: leftVal / rightVal}, true
+	case "%":
+		if rightVal == 0 {
+			return newError("division by zero"), true
+		}
+		if rightVal == -1 {
+			return &object.Integer{Value: 0}, true
+		}
+		return &object.Integer{Value: leftVal % rightVal}, true
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal), true
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal), true
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal), true
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal), true
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal), true
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal), true
+	default:
+		return nil, false
+	}
+}
+
+func evalInfixBigIntegerExpression(operator string, leftVal, rightVal *big.Int) object.Object {
+	switch operator {
+	case "+":
+		return normalizeBigInt(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return normalizeBigInt(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		return normalizeBigInt(new(big.Int).Mul(leftVal, rightVal))
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero")
+		}
+		return normalizeBigInt(new(big.Int).Quo(leftVal, rightVal))
+	case "%":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero")
+		}
+		return normalizeBigInt(new(big.Int).Rem(leftVal, rightVal))
+	case "**":
+		if rightVal.Sign() < 0 {
+			return evalInfixFloatExpression(operator, normalizeBigInt(leftVal), normalizeBigInt(rightVal))
+		}
+		return normalizeBigInt(new(big.Int).Exp(leftVal, rightVal, nil))
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError("unknown operator: %s %s %s",
+			normalizeBigInt(leftVal).Type(), operator, normalizeBigInt(rightVal).Type())
+	}
+}
+
+// isInteger returns true if object is integer or big integer
+func isInteger(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.BigInt:
+		return true
+	default:
+		return false
+	}
+}
+
+// toBigInt converts integer or big integer to *big.Int, it returns zero for other objects
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInt:
+		return obj.Value
+	default:
+		return new(big.Int)
+	}
+}
+
+// normalizeBigInt returns integer if value fits into int64, otherwise it returns big integer
+func normalizeBigInt(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+
+	return &object.BigInt{Value: value}
+}
@@ -0,0 +1,210 @@
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ythosa/pukiclang/src/ast"
+	"github.com/ythosa/pukiclang/src/object"
+)
+
+// DefaultMaxCallDepth is max depth of nested function calls when limits do not set it,
+// so infinite recursion returns error instead of overflowing stack of the host
+const DefaultMaxCallDepth = 10000
+
+// contextCheckInterval is number of evaluation steps between checks of execution context
+const contextCheckInterval = 256
+
+// Limits is type for limits of program execution, zero value of field means that the limit is not set
+type Limits = object.Limits
+
+// EvalContext evals node of AST tree like Eval, but stops evaluation with LimitError
+// when context is done or execution exceeds any of limits
+func EvalContext(ctx context.Context, node ast.Node, env *object.Environment, limits Limits) object.Object {
+	return withExecution(ctx, env, limits, func() object.Object {
+		return Eval(node, env)
+	})
+}
+
+// withExecution runs function within new execution of the program which environment belongs to
+func withExecution(ctx context.Context, env *object.Environment, limits Limits, run func() object.Object) object.Object {
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+
+	previous := env.Execution()
+	execution := &object.Execution{Context: ctx, Limits: limits}
+	if previous != nil {
+		execution.Output = previous.Output
+	}
+	env.SetExecution(execution)
+	defer env.SetExecution(previous)
+
+	return run()
+}
+
+// step counts evaluation step and returns error if execution has to be stopped
+func step(env *object.Environment) *object.Error {
+	execution := env.Execution()
+	if execution == nil {
+		return nil
+	}
+
+	execution.Steps++
+	if max := execution.Limits.MaxSteps; max > 0 && execution.Steps > max {
+		return newLimitError("step limit exceeded: %d", max)
+	}
+
+	if execution.Context != nil && (execution.Steps-1)%contextCheckInterval == 0 {
+		switch execution.Context.Err() {
+		case nil:
+		case context.DeadlineExceeded:
+			return newLimitError("timeout exceeded")
+		default:
+			return newLimitError("execution canceled")
+		}
+	}
+
+	return nil
+}
+
+// enterCall increases depth of nested function calls and returns error if it exceeds the limit
+func enterCall(env *object.Environment) *object.Error {
+	execution := env.Execution()
+	if execution == nil {
+		// depth of calls is tracked even if program is evaluated without limits
+		execution = &object.Execution{}
+		env.SetExecution(execution)
+	}
+
+	max := execution.Limits.MaxCallDepth
+	if max <= 0 {
+		max = DefaultMaxCallDepth
+	}
+
+	if execution.Depth >= max {
+		return newLimitError("call depth limit exceeded: %d", max)
+	}
+	execution.Depth++
+
+	return nil
+}
+
+// leaveCall decreases depth of nested function calls
+func leaveCall(env *object.Environment) {
+	env.Execution().Depth--
+}
+
+// checkAllocation returns error if evaluated string, array, hash or big integer is larger than the limit allows
+func checkAllocation(env *object.Environment, obj object.Object) *object.Error {
+	var size int64
+	switch obj := obj.(type) {
+	case *object.String:
+		size = int64(len(obj.Value))
+	case *object.Array:
+		size = int64(len(obj.Elements))
+	case *object.Hash:
+		size = int64(obj.Len())
+	case *object.BigInt:
+		size = bigIntSize(int64(obj.Value.BitLen()))
+	default:
+		return nil
+	}
+
+	return checkSize(env.Execution(), size)
+}
+
+// checkSize returns error if value of size which is about to be allocated exceeds allocation limit of execution,
+// size is length of string, array or hash or number of bytes of big integer
+func checkSize(execution *object.Execution, size int64) *object.Error {
+	if execution == nil || execution.Limits.MaxAllocation <= 0 {
+		return nil
+	}
+
+	if max := execution.Limits.MaxAllocation; size > int64(max) {
+		return newLimitError("allocation limit exceeded: %d", max)
+	}
+
+	return nil
+}
+
+// checkInfixAllocation returns error if result of infix expression would exceed allocation limit of execution,
+// so operands are checked before the result is computed
+func checkInfixAllocation(execution *object.Execution, operator string, left, right object.Object) *object.Error {
+	if execution == nil || execution.Limits.MaxAllocation <= 0 {
+		return nil
+	}
+
+	switch {
+	case left.Type() == object.StringObj && right.Type() == object.StringObj && operator == "+":
+		return checkSize(execution, int64(len(left.(*object.String).Value))+int64(len(right.(*object.String).Value)))
+
+	case isInteger(left) && isInteger(right):
+		// results which fit into int64 are not big integers and they are not limited
+		if bits := integerResultBits(operator, toBigInt(left), toBigInt(right)); bits > 64 {
+			return checkSize(execution, bigIntSize(bits))
+		}
+		return nil
+
+	default:
+		return nil
+	}
+}
+
+// evalLimitedInfixExpression evaluates infix expression like evalInfixExpression, but it returns LimitError
+// instead of computing result which would exceed allocation limit of execution
+func evalLimitedInfixExpression(execution *object.Execution, operator string, left, right object.Object) object.Object {
+	if err := checkInfixAllocation(execution, operator, left, right); err != nil {
+		return err
+	}
+
+	return evalInfixExpression(operator, left, right)
+}
+
+// integerResultBits returns upper bound of number of bits of result of integer infix expression
+func integerResultBits(operator string, left, right *big.Int) int64 {
+	leftBits, rightBits := int64(left.BitLen()), int64(right.BitLen())
+
+	switch operator {
+	case "+", "-":
+		if leftBits > rightBits {
+			return leftBits + 1
+		}
+		return rightBits + 1
+
+	case "*":
+		return leftBits + rightBits
+
+	case "**":
+		// powers of 0, 1 and -1 and negative exponents do not grow
+		if leftBits <= 1 || right.Sign() <= 0 {
+			return leftBits
+		}
+		if !right.IsInt64() || right.Int64() > math.MaxInt64/leftBits {
+			return math.MaxInt64
+		}
+		return leftBits * right.Int64()
+
+	default:
+		return leftBits
+	}
+}
+
+// bigIntSize returns number of bytes of big integer with number of bits
+func bigIntSize(bits int64) int64 {
+	return bits/8 + 1
+}
+
+func newLimitError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...), Kind: limitErrorType, Fatal: true}
+}
+
+// isLimitError returns true if object is error of exceeded limits, errors thrown with the same type are not
+func isLimitError(obj object.Object) bool {
+	err, ok := obj.(*object.Error)
+	return ok && err.Fatal
+}
@@ -0,0 +1,166 @@
+package evaluator
+
+import (
+	"math"
+	"unicode/utf8"
+
+	"github.com/ythosa/pukiclang/src/ast"
+	"github.com/ythosa/pukiclang/src/object"
+)
+
+func evalWhileExpression(we *ast.WhileExpression, env *object.Environment) object.Object {
+	for {
+		condition := Eval(we.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		result := Eval(we.Body, env)
+		if result == BREAK {
+			return NULL
+		}
+		if isError(result) || isReturnValue(result) {
+			return result
+		}
+	}
+}
+
+func evalForExpression(fe *ast.ForExpression, env *object.Environment) object.Object {
+	iterable := Eval(fe.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	iterator := newIterator(iterable)
+	if isError(iterator) {
+		return withPosition(iterator, fe.Iterable)
+	}
+
+	it := iterator.(*object.Iterator)
+	for {
+		key, value, ok := it.Next()
+		if !ok {
+			return NULL
+		}
+
+		if fe.Key != nil {
+			env.Set(fe.Key.Value, key)
+			env.Set(fe.Value.Value, value)
+		} else if it.ByKey {
+			env.Set(fe.Value.Value, key)
+		} else {
+			env.Set(fe.Value.Value, value)
+		}
+
+		result := Eval(fe.Body, env)
+		if result == BREAK {
+			return NULL
+		}
+		if isError(result) || isReturnValue(result) {
+			return result
+		}
+	}
+}
+
+// newIterator returns iterator over elements of arrays, characters of strings,
+// pairs of hashes and numbers of ranges or error if object is not iterable
+func newIterator(iterable object.Object) object.Object {
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		i := 0
+		return &object.Iterator{Next: func() (object.Object, object.Object, bool) {
+			if i >= len(iterable.Elements) {
+				return nil, nil, false
+			}
+
+			i++
+			return &object.Integer{Value: int64(i - 1)}, iterable.Elements[i-1], true
+		}}
+
+	case *object.String:
+		offset, i := 0, 0
+		return &object.Iterator{Next: func() (object.Object, object.Object, bool) {
+			if offset >= len(iterable.Value) {
+				return nil, nil, false
+			}
+
+			ch, size := utf8.DecodeRuneInString(iterable.Value[offset:])
+			offset += size
+			i++
+
+			return &object.Integer{Value: int64(i - 1)}, &object.String{Value: string(ch)}, true
+		}}
+
+	case *object.Hash:
+		pairs := iterable.Pairs()
+		i := 0
+		return &object.Iterator{ByKey: true, Next: func() (object.Object, object.Object, bool) {
+			if i >= len(pairs) {
+				return nil, nil, false
+			}
+
+			i++
+			return pairs[i-1].Key, pairs[i-1].Value, true
+		}}
+
+	case *object.Range:
+		current, i := iterable.Start, 0
+		done := (iterable.Step > 0 && current >= iterable.End) || (iterable.Step < 0 && current <= iterable.End)
+		return &object.Iterator{Next: func() (object.Object, object.Object, bool) {
+			if done {
+				return nil, nil, false
+			}
+
+			// the end is checked before advancing, so the last number is not advanced past int64 bounds
+			value := current
+			if rangeDistance(current, iterable.End, iterable.Step) <= rangeStep(iterable.Step) {
+				done = true
+			} else {
+				current += iterable.Step
+			}
+			i++
+
+			return &object.Integer{Value: int64(i - 1)}, &object.Integer{Value: value}, true
+		}}
+
+	default:
+		return newError("not iterable: %s", iterable.Type())
+	}
+}
+
+// rangeLength returns number of integers in range, it is clamped to math.MaxInt64
+func rangeLength(r *object.Range) int64 {
+	if (r.Step > 0 && r.Start >= r.End) || (r.Step < 0 && r.Start <= r.End) {
+		return 0
+	}
+
+	length := (rangeDistance(r.Start, r.End, r.Step)-1)/rangeStep(r.Step) + 1
+	if length > math.MaxInt64 {
+		return math.MaxInt64
+	}
+
+	return int64(length)
+}
+
+// rangeDistance returns distance from number to the end of range in direction of step,
+// number must not be past the end
+func rangeDistance(from, end, step int64) uint64 {
+	if step > 0 {
+		return uint64(end) - uint64(from)
+	}
+
+	return uint64(from) - uint64(end)
+}
+
+// rangeStep returns absolute value of step of range
+func rangeStep(step int64) uint64 {
+	if step > 0 {
+		return uint64(step)
+	}
+
+	return -uint64(step)
+}
@@ -0,0 +1,114 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/ythosa/pukiclang/src/object"
+)
+
+// isNumber returns true if object is integer, big integer or float
+func isNumber(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.BigInt, *object.Float:
+		return true
+	default:
+		return false
+	}
+}
+
+// toFloat converts number to float64, it returns 0 for other objects
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value
+	case *object.Float:
+		return obj.Value
+	default:
+		return 0
+	}
+}
+
+// roundingBuiltIn returns builtin which applies fn to float argument and returns integer argument as is
+func roundingBuiltIn(name string, fn func(float64) float64, doc string) *object.BuiltIn {
+	builtIn := &object.BuiltIn{Name: name, Params: []string{"INTEGER|FLOAT"}, Doc: doc}
+	builtIn.Fn = func(args ...object.Object) object.Object {
+		switch arg := args[0].(type) {
+		case *object.Integer, *object.BigInt:
+			return arg
+		case *object.Float:
+			return &object.Float{Value: fn(arg.Value)}
+		default:
+			return newError("argument to `%s` must be INTEGER or FLOAT, got %s", name, arg.Type())
+		}
+	}
+
+	return builtIn
+}
+
+func sqrt(args ...object.Object) object.Object {
+	if !isNumber(args[0]) {
+		return newError("argument to `sqrt` must be INTEGER or FLOAT, got %s", args[0].Type())
+	}
+
+	return &object.Float{Value: math.Sqrt(toFloat(args[0]))}
+}
+
+// pow returns integer if both arguments are integers and exponent is not negative, otherwise it returns float
+func pow(execution *object.Execution, args ...object.Object) object.Object {
+	for _, arg := range args {
+		if !isNumber(arg) {
+			return newError("arguments to `pow` must be INTEGER or FLOAT, got %s", arg.Type())
+		}
+	}
+
+	return evalLimitedInfixExpression(execution, "**", args[0], args[1])
+}
+
+func abs(args ...object.Object) object.Object {
+	switch arg := args[0].(type) {
+	case *object.Integer, *object.BigInt:
+		if evalInfixExpression("<", arg, &object.Integer{Value: 0}) == TRUE {
+			return evalMinusPrefixOperatorExpression(arg)
+		}
+		return arg
+	case *object.Float:
+		return &object.Float{Value: math.Abs(arg.Value)}
+	default:
+		return newError("argument to `abs` must be INTEGER or FLOAT, got %s", arg.Type())
+	}
+}
+
+// extremumBuiltIn returns builtin which picks number which is better than others by comparison operator,
+// it accepts numbers as arguments or single array of numbers
+func extremumBuiltIn(name string, operator string, doc string) *object.BuiltIn {
+	builtIn := &object.BuiltIn{Name: name, Params: []string{"INTEGER|FLOAT|ARRAY"}, Variadic: true, Doc: doc}
+	builtIn.Fn = func(args ...object.Object) object.Object {
+		if len(args) == 1 {
+			if arr, ok := args[0].(*object.Array); ok {
+				if len(arr.Elements) == 0 {
+					return newError("argument to `%s` must not be empty ARRAY", name)
+				}
+				args = arr.Elements
+			}
+		}
+
+		var result object.Object
+		for _, arg := range args {
+			if !isNumber(arg) {
+				return newError("arguments to `%s` must be INTEGER or FLOAT, got %s", name, arg.Type())
+			}
+
+			if result == nil || evalInfixExpression(operator, arg, result) == TRUE {
+				result = arg
+			}
+		}
+
+		return result
+	}
+
+	return builtIn
+}
@@ -0,0 +1,65 @@
+package evaluator
+
+import (
+	"github.com/ythosa/pukiclang/src/ast"
+	"github.com/ythosa/pukiclang/src/loader"
+	"github.com/ythosa/pukiclang/src/object"
+)
+
+// evalImportExpression evaluates module file once per program and returns module with its exported bindings,
+// which are read from environment of the module
+func evalImportExpression(node *ast.ImportExpression, env *object.Environment) object.Object {
+	path, err := loader.Resolve(node.Token.Pos.Filename, node.Path)
+	if err != nil {
+		return withPosition(newError("%s", err), node)
+	}
+
+	if module, ok := env.Module(path); ok {
+		if module == nil {
+			return withPosition(newError("import cycle: %s", node.Path), node)
+		}
+
+		return module
+	}
+
+	program, err := loader.Load(path)
+	if err != nil {
+		return withPosition(newError("%s", err), node)
+	}
+
+	env.SetModule(path, nil)
+
+	moduleEnv := object.NewModuleEnvironment(env)
+	if errObj, ok := Eval(program, moduleEnv).(*object.Error); ok {
+		env.ForgetModule(path)
+		errObj.Stack = append(errObj.Stack, object.StackFrame{Function: ModuleFunctionName(node.Path), Pos: node.Pos()})
+
+		return errObj
+	}
+
+	module := object.NewModule(node.Path, loader.Exports(program), moduleEnv)
+	env.SetModule(path, module)
+
+	return module
+}
+
+func evalModuleIndexExpression(module, index object.Object) object.Object {
+	name, ok := index.(*object.String)
+	if !ok {
+		return newError("index of module must be STRING, got %s", index.Type())
+	}
+
+	moduleObject := module.(*object.Module)
+
+	value, ok := moduleObject.Export(name.Value)
+	if !ok {
+		return newError("module %s has no exported binding: %s", moduleObject.Name, name.Value)
+	}
+
+	return value
+}
+
+// ModuleFunctionName returns name of module in stack traces of errors which are raised during its import
+func ModuleFunctionName(path string) string {
+	return "module " + path
+}
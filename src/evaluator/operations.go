@@ -0,0 +1,84 @@
+package evaluator
+
+import (
+	"context"
+
+	"github.com/ythosa/pukiclang/src/object"
+	"github.com/ythosa/pukiclang/src/token"
+)
+
+// Operations below are shared with the vm backend, so both backends produce same results
+
+// InfixOperation applies infix operator to evaluated operands
+func InfixOperation(operator string, left, right object.Object) object.Object {
+	return evalInfixExpression(operator, left, right)
+}
+
+// PrefixOperation applies prefix operator to evaluated operand
+func PrefixOperation(operator string, right object.Object) object.Object {
+	return evalPrefixExpression(operator, right)
+}
+
+// IndexOperation returns element of evaluated array, string or hash by index
+func IndexOperation(left, index object.Object) object.Object {
+	return evalIndexExpression(left, index)
+}
+
+// SetIndexOperation sets element of evaluated array or hash by index and returns assigned value
+func SetIndexOperation(left, index, value object.Object) object.Object {
+	return evalIndexAssignment(left, index, value)
+}
+
+// CallFunction calls evaluated function or built in function with arguments outside of the program,
+// e.g. from host application
+func CallFunction(fn object.Object, args []object.Object) object.Object {
+	result := unwrapReturnValue(applyFunction(fn, args, nil, token.Position{}))
+	if result == nil {
+		return NULL
+	}
+
+	return result
+}
+
+// CallFunctionContext calls function like CallFunction, but stops execution with LimitError
+// when context is done or execution exceeds any of limits
+func CallFunctionContext(ctx context.Context, fn object.Object, args []object.Object, limits Limits) object.Object {
+	function, ok := fn.(*object.Function)
+	if !ok {
+		return CallFunction(fn, args)
+	}
+
+	return withExecution(ctx, function.Env, limits, func() object.Object {
+		return CallFunction(fn, args)
+	})
+}
+
+// IsTruthy returns true if object is considered true in conditions
+func IsTruthy(obj object.Object) bool {
+	return isTruthy(obj)
+}
+
+// LookupBuiltIn returns standard built in function or namespace of built in functions by its name
+func LookupBuiltIn(name string) (object.Object, bool) {
+	return defaultBuiltIns.Lookup(name)
+}
+
+// ThrowOperation returns error which is thrown by throw statement with evaluated value
+func ThrowOperation(value object.Object) *object.Error {
+	return throwValue(value)
+}
+
+// ErrorValue returns hash which represents caught error in catch block
+func ErrorValue(errObj *object.Error) *object.Hash {
+	return errorToHash(errObj)
+}
+
+// Iterate returns iterator over evaluated iterable object of for loop or error
+func Iterate(iterable object.Object) object.Object {
+	return newIterator(iterable)
+}
+
+// NewError returns new error object with formatted message
+func NewError(format string, a ...interface{}) *object.Error {
+	return newError(format, a...)
+}
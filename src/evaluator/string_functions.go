@@ -0,0 +1,300 @@
+package evaluator
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ythosa/pukiclang/src/object"
+)
+
+// stringsNamespace is namespace of built in functions which work with strings
+const stringsNamespace = "strings"
+
+// stringBuiltIns returns built in functions of strings namespace
+func stringBuiltIns() []*object.BuiltIn {
+	return []*object.BuiltIn{
+		stringBuiltIn("split", 1, 1, "returns parts of string between separators, between whitespaces if separator is omitted",
+			func(args []string) object.Object {
+				if len(args) == 1 {
+					return stringsToArray(strings.Fields(args[0]))
+				}
+				return stringsToArray(strings.Split(args[0], args[1]))
+			}),
+		{Name: "strings.join", Fn: join, Params: []string{"ARRAY", "STRING"}, Optional: 1,
+			Doc: "returns strings of array concatenated with separator between them"},
+		stringBuiltIn("trim", 1, 1, "returns string without leading and trailing characters of cutset, whitespaces by default",
+			trimFunction(strings.TrimSpace, strings.Trim)),
+		stringBuiltIn("trimLeft", 1, 1, "returns string without leading characters of cutset, whitespaces by default",
+			trimFunction(func(s string) string { return strings.TrimLeftFunc(s, unicode.IsSpace) }, strings.TrimLeft)),
+		stringBuiltIn("trimRight", 1, 1, "returns string without trailing characters of cutset, whitespaces by default",
+			trimFunction(func(s string) string { return strings.TrimRightFunc(s, unicode.IsSpace) }, strings.TrimRight)),
+		stringBuiltIn("upper", 1, 0, "returns string with all letters in upper case",
+			func(args []string) object.Object { return &object.String{Value: strings.ToUpper(args[0])} }),
+		stringBuiltIn("lower", 1, 0, "returns string with all letters in lower case",
+			func(args []string) object.Object { return &object.String{Value: strings.ToLower(args[0])} }),
+		stringBuiltIn("contains", 2, 0, "returns true if string contains substring",
+			func(args []string) object.Object {
+				return nativeBoolToBooleanObject(strings.Contains(args[0], args[1]))
+			}),
+		stringBuiltIn("startsWith", 2, 0, "returns true if string begins with prefix",
+			func(args []string) object.Object {
+				return nativeBoolToBooleanObject(strings.HasPrefix(args[0], args[1]))
+			}),
+		stringBuiltIn("endsWith", 2, 0, "returns true if string ends with suffix",
+			func(args []string) object.Object {
+				return nativeBoolToBooleanObject(strings.HasSuffix(args[0], args[1]))
+			}),
+		stringBuiltIn("indexOf", 2, 0, "returns index of the first character of substring in string, -1 if it is not found",
+			func(args []string) object.Object {
+				i := strings.Index(args[0], args[1])
+				if i < 0 {
+					return &object.Integer{Value: -1}
+				}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(args[0][:i]))}
+			}),
+		stringBuiltIn("replace", 3, 0, "returns string with all occurrences of old substring replaced by new one",
+			func(args []string) object.Object {
+				return &object.String{Value: strings.ReplaceAll(args[0], args[1], args[2])}
+			}),
+		{Name: "strings.repeat", LimitedFn: repeat, Params: []string{"STRING", "INTEGER"},
+			Doc: "returns string repeated count times"},
+		{Name: "strings.substring", Fn: substring, Params: []string{"STRING", "INTEGER", "INTEGER"}, Optional: 1,
+			Doc: "returns characters of string from start to end exclusive, end is length of string by default"},
+		{Name: "strings.slice", Fn: slice, Params: []string{"STRING", "INTEGER", "INTEGER"}, Optional: 1,
+			Doc: "returns characters of string from start to end exclusive, negative indexes count from the end " +
+				"and indexes out of range are clamped"},
+		{Name: "strings.format", Fn: format, Params: []string{"STRING", "ANY"}, Optional: 1, Variadic: true,
+			Doc: "returns format with verbs replaced by arguments: %d for integer, %s for string, %v for any value, %% for percent sign"},
+	}
+}
+
+// stringBuiltIn returns built in function of strings namespace which accepts only strings
+func stringBuiltIn(name string, required, optional int, doc string, fn func(args []string) object.Object) *object.BuiltIn {
+	qualified := stringsNamespace + "." + name
+
+	params := make([]string, required+optional)
+	for i := range params {
+		params[i] = object.StringObj
+	}
+
+	return &object.BuiltIn{
+		Name:     qualified,
+		Params:   params,
+		Optional: optional,
+		Doc:      doc,
+		Fn: func(args ...object.Object) object.Object {
+			values := make([]string, len(args))
+			for i, arg := range args {
+				str, ok := arg.(*object.String)
+				if !ok {
+					return argumentError(qualified, i, object.StringObj, arg)
+				}
+				values[i] = str.Value
+			}
+
+			return fn(values)
+		},
+	}
+}
+
+// trimFunction returns function which trims whitespaces with trimSpace or characters of cutset with trimCutset
+func trimFunction(trimSpace func(string) string, trimCutset func(string, string) string) func([]string) object.Object {
+	return func(args []string) object.Object {
+		if len(args) == 1 {
+			return &object.String{Value: trimSpace(args[0])}
+		}
+
+		return &object.String{Value: trimCutset(args[0], args[1])}
+	}
+}
+
+func join(args ...object.Object) object.Object {
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return argumentError("strings.join", 0, object.ArrayObj, args[0])
+	}
+
+	separator := ""
+	if len(args) == 2 {
+		str, ok := args[1].(*object.String)
+		if !ok {
+			return argumentError("strings.join", 1, object.StringObj, args[1])
+		}
+		separator = str.Value
+	}
+
+	parts := make([]string, len(arr.Elements))
+	for i, element := range arr.Elements {
+		str, ok := element.(*object.String)
+		if !ok {
+			return newError("elements of array to `strings.join` must be STRING, got %s", element.Type())
+		}
+		parts[i] = str.Value
+	}
+
+	return &object.String{Value: strings.Join(parts, separator)}
+}
+
+func repeat(execution *object.Execution, args ...object.Object) object.Object {
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return argumentError("strings.repeat", 0, object.StringObj, args[0])
+	}
+
+	count, ok := args[1].(*object.Integer)
+	if !ok {
+		return argumentError("strings.repeat", 1, object.IntegerObj, args[1])
+	}
+
+	if count.Value < 0 {
+		return newError("count of `strings.repeat` must not be negative, got %d", count.Value)
+	}
+
+	if len(str.Value) > 0 && count.Value > int64(math.MaxInt32/len(str.Value)) {
+		return newError("result of `strings.repeat` is too long")
+	}
+
+	if errObj := checkSize(execution, int64(len(str.Value))*count.Value); errObj != nil {
+		return errObj
+	}
+
+	return &object.String{Value: strings.Repeat(str.Value, int(count.Value))}
+}
+
+func substring(args ...object.Object) object.Object {
+	chars, start, end, errObj := sliceArguments("strings.substring", args)
+	if errObj != nil {
+		return errObj
+	}
+
+	if start < 0 || end > int64(len(chars)) || start > end {
+		return newError("indexes of `strings.substring` out of range: [%d:%d] with length %d", start, end, len(chars))
+	}
+
+	return &object.String{Value: string(chars[start:end])}
+}
+
+func slice(args ...object.Object) object.Object {
+	chars, start, end, errObj := sliceArguments("strings.slice", args)
+	if errObj != nil {
+		return errObj
+	}
+
+	from, to := clampIndex(start, len(chars)), clampIndex(end, len(chars))
+	if from > to {
+		return &object.String{Value: ""}
+	}
+
+	return &object.String{Value: string(chars[from:to])}
+}
+
+// sliceArguments returns characters of string argument and start and end indexes of slice of them,
+// end is length of string if it is not passed
+func sliceArguments(name string, args []object.Object) ([]rune, int64, int64, *object.Error) {
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return nil, 0, 0, argumentError(name, 0, object.StringObj, args[0])
+	}
+	chars := []rune(str.Value)
+
+	indexes := []int64{0, int64(len(chars))}
+	for i, arg := range args[1:] {
+		index, ok := arg.(*object.Integer)
+		if !ok {
+			return nil, 0, 0, argumentError(name, i+1, object.IntegerObj, arg)
+		}
+		indexes[i] = index.Value
+	}
+
+	return chars, indexes[0], indexes[1], nil
+}
+
+// clampIndex returns index counted from the end if it is negative and limited to range [0, length]
+func clampIndex(index int64, length int) int {
+	if index < 0 {
+		index += int64(length)
+	}
+
+	switch {
+	case index < 0:
+		return 0
+	case index > int64(length):
+		return length
+	default:
+		return int(index)
+	}
+}
+
+func format(args ...object.Object) object.Object {
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return argumentError("strings.format", 0, object.StringObj, args[0])
+	}
+
+	var out strings.Builder
+	values := args[1:]
+	next := 0
+
+	for i := 0; i < len(str.Value); i++ {
+		ch := str.Value[i]
+		if ch != '%' {
+			out.WriteByte(ch)
+			continue
+		}
+
+		i++
+		if i == len(str.Value) {
+			return newError("format of `strings.format` ends with %%")
+		}
+
+		verb := str.Value[i]
+		if verb == '%' {
+			out.WriteByte('%')
+			continue
+		}
+
+		if next == len(values) {
+			return newError("missing argument for %%%c in `strings.format`", verb)
+		}
+		value := values[next]
+		next++
+
+		switch verb {
+		case 'd':
+			if !isInteger(value) {
+				return newError("%%d in `strings.format` requires INTEGER, got %s", value.Type())
+			}
+		case 's':
+			if value.Type() != object.StringObj {
+				return newError("%%s in `strings.format` requires STRING, got %s", value.Type())
+			}
+		case 'v':
+		default:
+			return newError("unknown verb %%%c in `strings.format`", verb)
+		}
+
+		out.WriteString(value.Inspect())
+	}
+
+	if next < len(values) {
+		return newError("too many arguments to `strings.format`: got=%d, want=%d", len(values), next)
+	}
+
+	return &object.String{Value: out.String()}
+}
+
+// argumentError returns error about argument with index i which has wrong type
+func argumentError(name string, i int, want string, got object.Object) *object.Error {
+	return newError("argument %d to `%s` must be %s, got %s", i+1, name, want, got.Type())
+}
+
+func stringsToArray(values []string) *object.Array {
+	elements := make([]object.Object, len(values))
+	for i, value := range values {
+		elements[i] = &object.String{Value: value}
+	}
+
+	return &object.Array{Elements: elements}
+}
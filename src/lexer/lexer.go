@@ -1,30 +1,77 @@
 package lexer
 
-import "github.com/ythosa/pukiclang/src/token"
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ythosa/pukiclang/src/token"
+)
 
 // Lexer is type for lexer which turns code into a sequence of tokens
 type Lexer struct {
 	input        string
-	position     int  // current position in input (points to current char)
-	readPosition int  // current reading position in input (after current char)
-	ch           byte // current char under examination
-	// todo: "ch" could be of <rune> type in the feature for the all unicode support
+	filename     string
+	position     int  // current byte position in input (points to current char)
+	readPosition int  // current byte reading position in input (after current char)
+	ch           rune // current char under examination
+	line         int  // line of current char
+	column       int  // column of current char, counted in chars
+	emitComments bool // true if comments are returned as tokens instead of being skipped
 }
 
 // New returns new lexer
 func New(input string) *Lexer {
-	l := Lexer{input: input}
+	return NewFile("", input)
+}
+
+// NewFile returns new lexer which stamps passed filename on token positions,
+// shebang line at the start of input is ignored
+func NewFile(filename string, input string) *Lexer {
+	l := Lexer{
+		input:    input,
+		filename: filename,
+		line:     1,
+	}
 	l.readChar()
 
+	if strings.HasPrefix(input, "#!") {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+	}
+
 	return &l
 }
 
+// SetEmitComments sets whether lexer returns comments as COMMENT tokens, comments are skipped by default
+func (l *Lexer) SetEmitComments(emit bool) {
+	l.emitComments = emit
+}
+
 // NextToken returns next token of the code
 func (l *Lexer) NextToken() token.Token {
+	for {
+		tok := l.nextToken()
+		if tok.Type != token.COMMENT || l.emitComments {
+			return tok
+		}
+	}
+}
+
+func (l *Lexer) nextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
 
+	pos := l.currentPosition()
+
+	if l.ch == '/' && (l.peekChar() == '/' || l.peekChar() == '*') {
+		tok = l.readComment()
+		tok.Pos = pos
+		return tok
+	}
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -54,14 +101,23 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.LT, l.ch)
 		}
-	case '+':
-		tok = newToken(token.PLUS, l.ch)
-	case '-':
-		tok = newToken(token.MINUS, l.ch)
-	case '/':
-		tok = newToken(token.SLASH, l.ch)
-	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+	case '+', '-', '/', '*', '%':
+		if l.ch == '*' && l.peekChar() == '*' {
+			tok = token.Token{Type: token.POWER, Literal: "**"}
+			l.readChar()
+		} else if l.peekChar() == '=' {
+			tok = makeCompoundAssignmentToken(l.ch)
+			l.readChar()
+		} else {
+			tok = newToken(arithmeticOperators[l.ch], l.ch)
+		}
+	case '&', '|':
+		if l.peekChar() == l.ch {
+			tok = makeLogicalOperatorToken(l.ch)
+			l.readChar()
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case ';':
@@ -79,33 +135,59 @@ func (l *Lexer) NextToken() token.Token {
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		str, terminated := l.readString()
+		if terminated {
+			tok.Type = token.STRING
+			tok.Literal = str
+		} else {
+			tok.Type = token.ILLEGAL
+			tok.Literal = `"` + str
+		}
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '.':
+		if isDigit(l.peekChar()) {
+			tok.Literal, tok.Type = l.readNumber()
+			tok.Pos = pos
+			return tok
+		}
+		tok = newToken(token.DOT, l.ch)
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
+		tok.Pos = pos
+		return tok
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos = pos
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok.Literal, tok.Type = l.readNumber()
+			tok.Pos = pos
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	}
 
+	tok.Pos = pos
 	l.readChar()
 
 	return tok
 }
 
-func newToken(tokenType token.Type, ch byte) token.Token {
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
+
+func newToken(tokenType token.Type, ch rune) token.Token {
 	return token.Token{
 		Type:    tokenType,
 		Literal: string(ch),
@@ -113,13 +195,20 @@ func newToken(tokenType token.Type, ch byte) token.Token {
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+
+	width := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition++
+	l.readPosition += width
+	l.column++
 }
 
 func (l *Lexer) skipWhitespace() {
@@ -128,12 +217,30 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	}
 
-	return l.input[l.readPosition]
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+
+	return ch
+}
+
+// peekSecondChar returns char after the next one
+func (l *Lexer) peekSecondChar() rune {
+	if l.readPosition >= len(l.input) {
+		return 0
+	}
+
+	_, width := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	if l.readPosition+width >= len(l.input) {
+		return 0
+	}
+
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition+width:])
+
+	return ch
 }
 
 func (l *Lexer) readIdentifier() string {
@@ -145,48 +252,135 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readString() string {
-	var str []byte
+// readString reads string literal and reports whether it has closing quote
+func (l *Lexer) readString() (string, bool) {
+	var str strings.Builder
 
 	for {
 		l.readChar()
 
+		escaped := l.ch == '\\'
+		if escaped {
+			l.readChar()
+		}
+
 		if l.ch == 0 {
+			return str.String(), false
+		}
+
+		if !escaped && l.ch == '"' {
 			break
 		}
 
-		if l.ch == '\\' {
+		str.WriteRune(l.ch)
+	}
+
+	return str.String(), true
+}
+
+// readComment reads line comment or block comment, block comments may be nested
+// and unterminated block comment is returned as ILLEGAL token
+func (l *Lexer) readComment() token.Token {
+	position := l.position
+
+	if l.peekChar() == '/' {
+		for l.ch != '\n' && l.ch != 0 {
 			l.readChar()
 		}
 
-		if l.input[l.position-1] != '\\' && l.ch == '"' {
-			break
-		}
+		return token.Token{Type: token.COMMENT, Literal: strings.TrimRight(l.input[position:l.position], "\r")}
+	}
 
-		str = append(str, l.ch)
+	l.readChar()
+	l.readChar()
+
+	for depth := 1; depth > 0; l.readChar() {
+		switch {
+		case l.ch == 0:
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[position:]}
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+		}
 	}
 
-	return string(str[:])
+	return token.Token{Type: token.COMMENT, Literal: l.input[position:l.position]}
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads integer or float literal, float literal has fraction part, exponent or both
+func (l *Lexer) readNumber() (string, token.Type) {
 	position := l.position
-	for isDigit(l.ch) {
+	tokenType := token.Type(token.INT)
+
+	l.readDigits()
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
 		l.readChar()
+		l.readDigits()
 	}
 
-	return l.input[position:l.position]
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || ((next == '+' || next == '-') && isDigit(l.peekSecondChar())) {
+			tokenType = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			l.readDigits()
+		}
+	}
+
+	return l.input[position:l.position], tokenType
+}
+
+func (l *Lexer) readDigits() {
+	for isDigit(l.ch) {
+		l.readChar()
+	}
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func makeTwoCharComparisonToken(current byte) token.Token {
+var arithmeticOperators = map[rune]token.Type{
+	'+': token.PLUS,
+	'-': token.MINUS,
+	'/': token.SLASH,
+	'*': token.ASTERISK,
+	'%': token.PERCENT,
+}
+
+var compoundAssignmentOperators = map[rune]token.Type{
+	'+': token.PLUSASSIGN,
+	'-': token.MINUSASSIGN,
+	'/': token.SLASHASSIGN,
+	'*': token.ASTERISKASSIGN,
+	'%': token.PERCENTASSIGN,
+}
+
+func makeCompoundAssignmentToken(current rune) token.Token {
+	return token.Token{Type: compoundAssignmentOperators[current], Literal: string(current) + "="}
+}
+
+func makeLogicalOperatorToken(current rune) token.Token {
+	if current == '&' {
+		return token.Token{Type: token.AND, Literal: "&&"}
+	}
+
+	return token.Token{Type: token.OR, Literal: "||"}
+}
+
+func makeTwoCharComparisonToken(current rune) token.Token {
 	if current == '=' {
 		return token.Token{Type: token.EQ, Literal: "=="}
 	}
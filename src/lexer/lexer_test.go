@@ -16,7 +16,7 @@ let add = fn(x, y) {
 
 let result = add(five, ten);
 
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -151,3 +151,256 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  x + "a
+b" ;`
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+		expectedOffset  int
+	}{
+		{"let", 1, 1, 0},
+		{"x", 1, 5, 4},
+		{"=", 1, 7, 6},
+		{"5", 1, 9, 8},
+		{";", 1, 10, 9},
+		{"x", 2, 3, 13},
+		{"+", 2, 5, 15},
+		{"a\nb", 2, 7, 17},
+		{";", 3, 4, 23},
+		{"", 3, 5, 24},
+	}
+
+	l := lexer.NewFile("main.puki", input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.Filename != "main.puki" {
+			t.Fatalf("tests[%d] - filename wrong. got=%q", i, tok.Pos.Filename)
+		}
+
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedColumn, tok.Pos.Line, tok.Pos.Column)
+		}
+
+		if tok.Pos.Offset != tt.expectedOffset {
+			t.Fatalf("tests[%d] - offset wrong. expected=%d, got=%d",
+				i, tt.expectedOffset, tok.Pos.Offset)
+		}
+	}
+}
+
+func TestAssignmentOperators(t *testing.T) {
+	input := `x += 1; x -= 2; x *= 3; x /= 4; x = +5; x %= 2 ** 3 % 4`
+
+	expected := []token.Type{
+		token.IDENT, token.PLUSASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.MINUSASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.ASTERISKASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.SLASHASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.ASSIGN, token.PLUS, token.INT, token.SEMICOLON,
+		token.IDENT, token.PERCENTASSIGN, token.INT, token.POWER, token.INT, token.PERCENT, token.INT,
+		token.EOF,
+	}
+
+	l := lexer.New(input)
+	for i, expectedType := range expected {
+		tok := l.NextToken()
+
+		if tok.Type != expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, expectedType, tok.Type)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	input := `1 1.5 .5 1e9 2.5E-3 1e+2 1. 1e x.5`
+
+	expected := []token.Token{
+		{Type: token.INT, Literal: "1"},
+		{Type: token.FLOAT, Literal: "1.5"},
+		{Type: token.FLOAT, Literal: ".5"},
+		{Type: token.FLOAT, Literal: "1e9"},
+		{Type: token.FLOAT, Literal: "2.5E-3"},
+		{Type: token.FLOAT, Literal: "1e+2"},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.DOT, Literal: "."},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.IDENT, Literal: "e"},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.FLOAT, Literal: ".5"},
+		{Type: token.EOF, Literal: ""},
+	}
+
+	l := lexer.New(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+
+		if tok.Type != tt.Type || tok.Literal != tt.Literal {
+			t.Fatalf("tests[%d] - token wrong. expected=%q %q, got=%q %q",
+				i, tt.Type, tt.Literal, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	input := `a && b || c & d`
+
+	expected := []token.Token{
+		{Type: token.IDENT, Literal: "a"},
+		{Type: token.AND, Literal: "&&"},
+		{Type: token.IDENT, Literal: "b"},
+		{Type: token.OR, Literal: "||"},
+		{Type: token.IDENT, Literal: "c"},
+		{Type: token.ILLEGAL, Literal: "&"},
+		{Type: token.IDENT, Literal: "d"},
+		{Type: token.EOF, Literal: ""},
+	}
+
+	l := lexer.New(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+
+		if tok.Type != tt.Type || tok.Literal != tt.Literal {
+			t.Fatalf("tests[%d] - token wrong. expected=%q %q, got=%q %q",
+				i, tt.Type, tt.Literal, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestUnterminatedString(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+	}{
+		{`"hello`, `"hello`},
+		{`"hello \"`, `"hello "`},
+		{`"hello\`, `"hello`},
+	}
+
+	for _, tt := range tests {
+		tok := lexer.New(tt.input).NextToken()
+
+		if tok.Type != token.ILLEGAL {
+			t.Fatalf("tokentype wrong. expected=%q, got=%q", token.ILLEGAL, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("literal wrong. expected=%q, got=%q", tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestUnicodeInput(t *testing.T) {
+	input := `let привет = "мир 🌍";
+пр_ивет`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "привет", 5},
+		{token.ASSIGN, "=", 12},
+		{token.STRING, "мир 🌍", 14},
+		{token.SEMICOLON, ";", 21},
+		{token.IDENT, "пр_ивет", 1},
+		{token.EOF, "", 8},
+	}
+
+	l := lexer.New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - column wrong. expected=%d, got=%d",
+				i, tt.expectedColumn, tok.Pos.Column)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `#!/usr/bin/env pukiclang
+let x = 10 / 2; // line comment
+/* block /* nested */ comment */ x /= 2 /**/
+// last`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{token.LET, "let", 2, 1},
+		{token.IDENT, "x", 2, 5},
+		{token.ASSIGN, "=", 2, 7},
+		{token.INT, "10", 2, 9},
+		{token.SLASH, "/", 2, 12},
+		{token.INT, "2", 2, 14},
+		{token.SEMICOLON, ";", 2, 15},
+		{token.COMMENT, "// line comment", 2, 17},
+		{token.COMMENT, "/* block /* nested */ comment */", 3, 1},
+		{token.IDENT, "x", 3, 34},
+		{token.SLASHASSIGN, "/=", 3, 36},
+		{token.INT, "2", 3, 39},
+		{token.COMMENT, "/**/", 3, 41},
+		{token.COMMENT, "// last", 4, 1},
+		{token.EOF, "", 4, 8},
+	}
+
+	for _, emit := range []bool{true, false} {
+		l := lexer.New(input)
+		l.SetEmitComments(emit)
+
+		for i, tt := range tests {
+			if tt.expectedType == token.COMMENT && !emit {
+				continue
+			}
+
+			tok := l.NextToken()
+
+			if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+				t.Fatalf("tests[%d] - token wrong. expected=%q %q, got=%q %q",
+					i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+			}
+
+			if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+				t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+					i, tt.expectedLine, tt.expectedColumn, tok.Pos.Line, tok.Pos.Column)
+			}
+		}
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	tests := []string{"/* comment", "/* outer /* inner */", "/*/"}
+
+	for _, input := range tests {
+		tok := lexer.New(input).NextToken()
+
+		if tok.Type != token.ILLEGAL || tok.Literal != input {
+			t.Fatalf("token wrong. expected=%q %q, got=%q %q", token.ILLEGAL, input, tok.Type, tok.Literal)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package loader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ythosa/pukiclang/src/ast"
+	"github.com/ythosa/pukiclang/src/lexer"
+	"github.com/ythosa/pukiclang/src/parser"
+)
+
+// Extension is extension of source files which is added to imported paths without it
+const Extension = ".puki"
+
+// SearchPathVariable is name of environment variable with list of directories where modules are searched
+const SearchPathVariable = "PUKIPATH"
+
+// Resolve returns absolute path of module file which is imported by the importer file.
+// Paths which start with ./ or ../ are relative to directory of the importer,
+// other relative paths are searched in directory of the importer and then in directories of PUKIPATH.
+// Directory of the importer is the working directory if the importer is not a file (e.g. REPL input)
+func Resolve(importer string, path string) (string, error) {
+	if filepath.Ext(path) != Extension {
+		path += Extension
+	}
+
+	if filepath.IsAbs(path) {
+		return existingFile(path)
+	}
+
+	dirs := []string{importerDir(importer)}
+	if !isExplicitlyRelative(path) {
+		dirs = append(dirs, SearchPath()...)
+	}
+
+	for _, dir := range dirs {
+		if resolved, err := existingFile(filepath.Join(dir, path)); err == nil {
+			return resolved, nil
+		}
+	}
+
+	return "", fmt.Errorf("module not found: %s", strings.TrimSuffix(path, Extension))
+}
+
+// SearchPath returns directories from PUKIPATH environment variable
+func SearchPath() []string {
+	var dirs []string
+
+	for _, dir := range filepath.SplitList(os.Getenv(SearchPathVariable)) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
+
+// Load reads and parses module file, syntax errors of the module are returned as error
+func Load(filename string) (*ast.Program, error) {
+	source, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read module: %s", err)
+	}
+
+	p := parser.New(lexer.NewFile(filename, string(source)))
+
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) != 0 {
+		return nil, fmt.Errorf("syntax error in module: %s", errors[0].Error())
+	}
+
+	return program, nil
+}
+
+// Exports returns names of bindings which are exported by top level statements of the program
+func Exports(program *ast.Program) []string {
+	var names []string
+
+	for _, stmt := range program.Statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			names = append(names, export.Statement.Name.Value)
+		}
+	}
+
+	return names
+}
+
+func importerDir(importer string) string {
+	if info, err := os.Stat(importer); err == nil && info.Mode().IsRegular() {
+		return filepath.Dir(importer)
+	}
+
+	return "."
+}
+
+func isExplicitlyRelative(path string) bool {
+	return strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") ||
+		strings.HasPrefix(path, "."+string(filepath.Separator)) ||
+		strings.HasPrefix(path, ".."+string(filepath.Separator))
+}
+
+func existingFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a file", path)
+	}
+
+	return filepath.Abs(path)
+}
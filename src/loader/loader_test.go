@@ -0,0 +1,71 @@
+package loader_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ythosa/pukiclang/src/loader"
+)
+
+func TestResolve(t *testing.T) {
+	root, err := ioutil.TempDir("", "loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := []string{"app/main.puki", "app/local.puki", "app/shared.puki", "lib/shared.puki", "lib/only.puki"}
+	for _, file := range files {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Setenv(loader.SearchPathVariable, filepath.Join(root, "lib")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv(loader.SearchPathVariable)
+
+	importer := filepath.Join(root, "app/main.puki")
+
+	tests := []struct {
+		path     string
+		expected string // empty if module must not be found
+	}{
+		{"local", "app/local.puki"},
+		{"./local", "app/local.puki"},
+		{"local.puki", "app/local.puki"},
+		{"shared", "app/shared.puki"},
+		{"only", "lib/only.puki"},
+		{"../lib/only", "lib/only.puki"},
+		{"./only", ""},
+		{"missing", ""},
+		{filepath.Join(root, "lib/only"), "lib/only.puki"},
+	}
+
+	for _, tt := range tests {
+		resolved, err := loader.Resolve(importer, tt.path)
+
+		if tt.expected == "" {
+			if err == nil {
+				t.Errorf("module %q must not be found, got=%s", tt.path, resolved)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("module %q is not found: %s", tt.path, err)
+			continue
+		}
+
+		if resolved != filepath.Join(root, tt.expected) {
+			t.Errorf("wrong path of module %q. expected=%s, got=%s", tt.path, filepath.Join(root, tt.expected), resolved)
+		}
+	}
+}
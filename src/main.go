@@ -1,11 +1,21 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/user"
 
+	"github.com/ythosa/pukiclang/src/ast"
+	"github.com/ythosa/pukiclang/src/compiler"
+	"github.com/ythosa/pukiclang/src/evaluator"
+	"github.com/ythosa/pukiclang/src/lexer"
+	"github.com/ythosa/pukiclang/src/object"
+	"github.com/ythosa/pukiclang/src/parser"
 	"github.com/ythosa/pukiclang/src/repl"
+	"github.com/ythosa/pukiclang/src/vm"
 )
 
 const pukiclang = `
@@ -19,14 +29,212 @@ const pukiclang = `
 　＼二つ
 `
 
+const usage = `Usage:
+  pukiclang                       start interactive REPL
+  pukiclang run <file> [args...]  execute script file
+  pukiclang -e <code> [args...]   execute code passed as argument
+  <command> | pukiclang [args...] execute code read from stdin
+
+Flags:
+`
+
+// Engines which execute programs
+const (
+	engineEval = "eval" // tree-walking evaluator
+	engineVM   = "vm"   // bytecode compiler and virtual machine
+)
+
+// Exit codes of the interpreter
+const (
+	exitOK    = 0
+	exitError = 1 // syntax error or uncaught runtime error
+	exitUsage = 2
+)
+
 func main() {
+	os.Exit(runCLI(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// cli is type for command line interface of the interpreter with its standard streams
+type cli struct {
+	flags  *flag.FlagSet
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// runCLI runs the interpreter with command line arguments and standard streams and returns exit code
+func runCLI(arguments []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{flags: flag.NewFlagSet("pukiclang", flag.ContinueOnError), stdin: stdin, stdout: stdout, stderr: stderr}
+
+	c.flags.SetOutput(stderr)
+	c.flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		c.flags.PrintDefaults()
+	}
+	code := c.flags.String("e", "", "execute passed code and print its result")
+	engine := c.flags.String("engine", engineEval, "engine which executes scripts: eval or vm (the REPL always uses eval)")
+
+	if err := c.flags.Parse(arguments); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if *engine != engineEval && *engine != engineVM {
+		fmt.Fprintf(stderr, "unknown engine: %s\n", *engine)
+		c.flags.Usage()
+		return exitUsage
+	}
+
+	// empty code passed with -e is executed too, so it is not confused with missing flag
+	var passedCode *string
+	c.flags.Visit(func(f *flag.Flag) {
+		if f.Name == "e" {
+			passedCode = code
+		}
+	})
+
+	return c.execute(passedCode, *engine, c.flags.Args())
+}
+
+// execute runs code passed with -e flag if it is not nil, script, source from stdin or the REPL
+func (c *cli) execute(code *string, engine string, args []string) int {
+	switch {
+	case code != nil:
+		return c.run(engine, "-e", *code, args, true)
+
+	case len(args) > 0 && args[0] == "run":
+		if len(args) < 2 {
+			c.flags.Usage()
+			return exitUsage
+		}
+
+		source, err := ioutil.ReadFile(args[1])
+		if err != nil {
+			fmt.Fprintln(c.stderr, err)
+			return exitError
+		}
+
+		return c.run(engine, args[1], string(source), args[2:], false)
+
+	case !isTerminal(c.stdin):
+		source, err := ioutil.ReadAll(c.stdin)
+		if err != nil {
+			fmt.Fprintln(c.stderr, err)
+			return exitError
+		}
+
+		return c.run(engine, "<stdin>", string(source), args, true)
+
+	default:
+		startREPL(c.stdin, c.stdout)
+		return exitOK
+	}
+}
+
+// run evaluates source and returns exit code which reflects the result of evaluation
+func (c *cli) run(engine, filename, source string, args []string, printResult bool) int {
+	l := lexer.NewFile(filename, source)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printErrors(c.stderr, p.Errors())
+		return exitError
+	}
+
+	var evaluated object.Object
+	if engine == engineVM {
+		var err error
+		if evaluated, err = runVM(program, args, c.stdout); err != nil {
+			fmt.Fprintln(c.stderr, err)
+			return exitError
+		}
+	} else {
+		evaluated = runEval(program, args, c.stdout)
+	}
+
+	if errObj, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintln(c.stderr, errObj.Traceback())
+		return exitError
+	}
+
+	if printResult && evaluated != nil && evaluated != evaluator.NULL {
+		fmt.Fprintln(c.stdout, evaluated.Inspect())
+	}
+
+	return exitOK
+}
+
+func runEval(program *ast.Program, args []string, out io.Writer) object.Object {
+	env := object.NewEnvironment()
+	env.SetExecution(&object.Execution{Output: out})
+	env.Set("args", argsToArray(args))
+
+	return evaluator.Eval(program, env)
+}
+
+func runVM(program *ast.Program, args []string, out io.Writer) (object.Object, error) {
+	symbolTable := compiler.NewSymbolTable()
+	argsSymbol := symbolTable.Define("args")
+
+	comp := compiler.NewWithState(symbolTable, []object.Object{})
+	if err := comp.Compile(program); err != nil {
+		return nil, err
+	}
+
+	globals := make([]object.Object, vm.GlobalsSize)
+	globals[argsSymbol.Index] = argsToArray(args)
+
+	machine := vm.NewWithGlobals(comp.Bytecode(), globals)
+	machine.SetOutput(out)
+	if err := machine.Run(); err != nil {
+		return nil, err
+	}
+
+	return machine.Result(), nil
+}
+
+func argsToArray(args []string) *object.Array {
+	elements := make([]object.Object, len(args))
+	for i, arg := range args {
+		elements[i] = &object.String{Value: arg}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+func printErrors(out io.Writer, errors []parser.Diagnostic) {
+	for _, d := range errors {
+		fmt.Fprintln(out, d.Error())
+	}
+}
+
+// isTerminal returns true if input is a terminal, readers which are not files are never terminals
+func isTerminal(in io.Reader) bool {
+	f, ok := in.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func startREPL(in io.Reader, out io.Writer) {
 	u, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
-	fmt.Print(pukiclang)
-	fmt.Printf("Hello %s! This is the pukiclang programming language!\n",
+	fmt.Fprint(out, pukiclang)
+	fmt.Fprintf(out, "Hello %s! This is the pukiclang programming language!\n",
 		u.Username)
-	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	fmt.Fprintf(out, "Feel free to type in commands\n")
+	repl.Start(in, out)
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCLI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "script.puki")
+	if err := ioutil.WriteFile(script, []byte(`if (args != ["a", "b"]) { throw "wrong args" }; 1 + 2`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args           []string
+		stdin          string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string // substring of stderr, stderr must be empty if it is not set
+	}{
+		{[]string{"-e", "1 + 2"}, "", exitOK, "3\n", ""},
+		{[]string{"-e", "let x = 1"}, "", exitOK, "", ""},
+		{[]string{"-e", ""}, "1 + 2", exitOK, "", ""},
+		{[]string{"-e", `puts("a", [1]); puts(fn(x) { x }(2))`}, "", exitOK, "a\n[1]\n2\n", ""},
+		{[]string{"-e", `map([1, 2], puts)`}, "", exitOK, "1\n2\n[null, null]\n", ""},
+		{[]string{"-e", "args", "a", "b"}, "", exitOK, "[a, b]\n", ""},
+		{[]string{"-e", "let x = ;"}, "", exitError, "", "-e:1:9: unexpected ;, expected expression"},
+		{[]string{"-e", "1 / 0"}, "", exitError, "", "-e:1:3: division by zero"},
+		{[]string{"-e", `throw "boom"`}, "", exitError, "", "boom"},
+		{nil, "1 + 2", exitOK, "3\n", ""},
+		{[]string{"x"}, "args[0]", exitOK, "x\n", ""},
+		{nil, "let x = ;", exitError, "", "<stdin>:1:9: unexpected ;"},
+		{[]string{"run", script, "a", "b"}, "", exitOK, "", ""},
+		{[]string{"run", script}, "", exitError, "", "wrong args"},
+		{[]string{"run", filepath.Join(dir, "missing.puki")}, "", exitError, "", "missing.puki"},
+		{[]string{"run"}, "", exitUsage, "", "Usage:"},
+		{[]string{"-engine", "js", "-e", "1"}, "", exitUsage, "", "unknown engine: js"},
+		{[]string{"-unknown"}, "", exitUsage, "", "flag provided but not defined: -unknown"},
+	}
+
+	for _, engine := range []string{engineEval, engineVM} {
+		for _, tt := range tests {
+			args := append([]string{"-engine", engine}, tt.args...)
+			var stdout, stderr bytes.Buffer
+
+			code := runCLI(args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.expectedCode {
+				t.Errorf("wrong exit code for %q. expected=%d, got=%d (stderr %q)", args, tt.expectedCode, code, stderr.String())
+			}
+
+			if stdout.String() != tt.expectedStdout {
+				t.Errorf("wrong stdout for %q. expected=%q, got=%q", args, tt.expectedStdout, stdout.String())
+			}
+
+			if (tt.expectedStderr == "") != (stderr.Len() == 0) || !strings.Contains(stderr.String(), tt.expectedStderr) {
+				t.Errorf("wrong stderr for %q. expected to contain %q, got=%q", args, tt.expectedStderr, stderr.String())
+			}
+		}
+	}
+}
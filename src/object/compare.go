@@ -0,0 +1,221 @@
+package object
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Equal returns true if objects are structurally equal: numbers are equal by value, strings by characters,
+// arrays by elements, hashes by pairs and ranges by bounds, other objects are equal only if they are identical
+func Equal(a, b Object) bool {
+	return (&comparison{}).equal(a, b)
+}
+
+// Compare returns -1, 0 or 1 if a is less than, equal to or greater than b,
+// numbers are ordered by value, strings by bytes and arrays lexicographically by elements,
+// error is returned for objects which are not ordered
+func Compare(a, b Object) (int, error) {
+	return (&comparison{}).compare(a, b)
+}
+
+// objectPair is type for pair of compared objects
+type objectPair struct {
+	a, b Object
+}
+
+// comparison is type for state of structural comparison, it remembers containers which are being compared,
+// so comparison of containers which contain themselves terminates
+type comparison struct {
+	visited map[objectPair]bool
+}
+
+// enter returns false if containers are already being compared, in this case they are considered equal
+func (c *comparison) enter(a, b Object) bool {
+	if c.visited == nil {
+		c.visited = make(map[objectPair]bool)
+	}
+
+	pair := objectPair{a, b}
+	if c.visited[pair] {
+		return false
+	}
+	c.visited[pair] = true
+
+	return true
+}
+
+func (c *comparison) equal(a, b Object) bool {
+	if isNumberObject(a) && isNumberObject(b) {
+		result, ok := compareNumbers(a, b)
+		return ok && result == 0
+	}
+
+	if a == b {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+
+	case *Range:
+		b, ok := b.(*Range)
+		return ok && *a == *b
+
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		if !c.enter(a, b) {
+			return true
+		}
+
+		for i, element := range a.Elements {
+			if !c.equal(element, b.Elements[i]) {
+				return false
+			}
+		}
+
+		return true
+
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+		if !c.enter(a, b) {
+			return true
+		}
+
+		for _, pair := range a.Pairs() {
+			other, ok := b.Get(pair.Key)
+			if !ok || !c.equal(pair.Value, other.Value) {
+				return false
+			}
+		}
+
+		return true
+
+	default:
+		return false
+	}
+}
+
+func (c *comparison) compare(a, b Object) (int, error) {
+	if isNumberObject(a) && isNumberObject(b) {
+		result, ok := compareNumbers(a, b)
+		if !ok {
+			return 0, fmt.Errorf("cannot compare NaN")
+		}
+		return result, nil
+	}
+
+	switch a := a.(type) {
+	case *String:
+		if b, ok := b.(*String); ok {
+			switch {
+			case a.Value < b.Value:
+				return -1, nil
+			case a.Value > b.Value:
+				return 1, nil
+			default:
+				return 0, nil
+			}
+		}
+
+	case *Array:
+		if b, ok := b.(*Array); ok {
+			if !c.enter(a, b) {
+				return 0, nil
+			}
+
+			for i := 0; i < len(a.Elements) && i < len(b.Elements); i++ {
+				result, err := c.compare(a.Elements[i], b.Elements[i])
+				if err != nil || result != 0 {
+					return result, err
+				}
+			}
+
+			return compareInts(len(a.Elements), len(b.Elements)), nil
+		}
+	}
+
+	if a.Type() == b.Type() {
+		return 0, fmt.Errorf("%s is not ordered", a.Type())
+	}
+
+	return 0, fmt.Errorf("cannot compare %s with %s", a.Type(), b.Type())
+}
+
+func isNumberObject(obj Object) bool {
+	switch obj.(type) {
+	case *Integer, *BigInt, *Float:
+		return true
+	default:
+		return false
+	}
+}
+
+// compareNumbers compares numbers by value, integers are compared exactly and integer is promoted to float
+// if other number is float, it returns false if any of numbers is NaN
+func compareNumbers(a, b Object) (int, bool) {
+	_, aFloat := a.(*Float)
+	_, bFloat := b.(*Float)
+
+	if !aFloat && !bFloat {
+		return bigIntOf(a).Cmp(bigIntOf(b)), true
+	}
+
+	x, y := floatOf(a), floatOf(b)
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	case x == y:
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+func bigIntOf(obj Object) *big.Int {
+	if i, ok := obj.(*Integer); ok {
+		return big.NewInt(i.Value)
+	}
+
+	return obj.(*BigInt).Value
+}
+
+func floatOf(obj Object) float64 {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value)
+	case *BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value
+	default:
+		return obj.(*Float).Value
+	}
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,25 @@
+package object
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Limits is type for limits of program execution, zero value of field means that the limit is not set
+type Limits struct {
+	MaxSteps      int64         // max number of evaluated nodes
+	MaxCallDepth  int           // max depth of nested function calls
+	MaxAllocation int           // max length of created string, array or hash, or number of bytes of big integer
+	Timeout       time.Duration // max wall-clock time of execution
+}
+
+// Execution is type for state of program execution which is checked against its limits
+type Execution struct {
+	Context context.Context
+	Limits  Limits
+	Steps   int64 // number of evaluated nodes
+	Depth   int   // depth of nested function calls
+
+	Output io.Writer // writer which printed values are written to, standard output is used if it is nil
+}
@@ -2,11 +2,18 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/ythosa/pukiclang/src/ast"
+	"github.com/ythosa/pukiclang/src/code"
+	"github.com/ythosa/pukiclang/src/token"
 )
 
 // Type is type of object which represented with string
@@ -18,21 +25,80 @@ func NewEnvironment() *Environment {
 	return &Environment{
 		store: s,
 		outer: nil,
+		runtime: &runtime{
+			modules: make(map[string]*Module),
+		},
 	}
 }
 
 // NewEnclosedEnvironment returns new environment with pointer on outer environment
 func NewEnclosedEnvironment(outer *Environment) *Environment {
-	env := NewEnvironment()
-	env.outer = outer
+	return &Environment{
+		store:   make(map[string]Object),
+		outer:   outer,
+		runtime: outer.runtime,
+	}
+}
 
-	return env
+// NewModuleEnvironment returns new top level environment of module which shares imported modules
+// and execution with importer
+func NewModuleEnvironment(importer *Environment) *Environment {
+	return &Environment{
+		store:   make(map[string]Object),
+		runtime: importer.runtime,
+	}
 }
 
 // Environment is type for environment
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store   map[string]Object
+	outer   *Environment
+	runtime *runtime // state shared by all environments of the program
+}
+
+// runtime is type for state of the program which is shared by all its environments
+type runtime struct {
+	modules   map[string]*Module // modules by paths of their files
+	execution *Execution         // current execution of the program, nil if it is not started with limits
+	builtIns  *Registry          // built in functions of the program, nil for the standard ones
+}
+
+// Module returns module imported from file and is the file imported,
+// module is nil if the file is imported but its evaluation is not finished yet
+func (e *Environment) Module(path string) (*Module, bool) {
+	module, ok := e.runtime.modules[path]
+	return module, ok
+}
+
+// SetModule records module imported from file, nil module marks that evaluation of the file is not finished yet
+func (e *Environment) SetModule(path string, module *Module) {
+	e.runtime.modules[path] = module
+}
+
+// ForgetModule removes module imported from file, so the file is evaluated again on the next import
+func (e *Environment) ForgetModule(path string) {
+	delete(e.runtime.modules, path)
+}
+
+// Execution returns current execution of the program which environment belongs to
+func (e *Environment) Execution() *Execution {
+	return e.runtime.execution
+}
+
+// SetExecution sets current execution of the program which environment belongs to
+func (e *Environment) SetExecution(execution *Execution) {
+	e.runtime.execution = execution
+}
+
+// BuiltIns returns registry of built in functions of the program which environment belongs to,
+// it is nil if the program uses the standard built in functions
+func (e *Environment) BuiltIns() *Registry {
+	return e.runtime.builtIns
+}
+
+// SetBuiltIns sets registry of built in functions of the program which environment belongs to
+func (e *Environment) SetBuiltIns(registry *Registry) {
+	e.runtime.builtIns = registry
 }
 
 // Get returns object in environment and is environment contains it with passed name
@@ -45,12 +111,36 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
+// Names returns sorted names of variables defined directly in environment
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
 // Set in environment variable with key = `name` and value = `value`
 func (e *Environment) Set(name string, value Object) Object {
 	e.store[name] = value
 	return value
 }
 
+// Assign sets value of variable in the environment where it is defined,
+// it returns false if variable is not defined in the environment and its outers
+func (e *Environment) Assign(name string, value Object) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = value
+			return true
+		}
+	}
+
+	return false
+}
+
 // Object interface
 type Object interface {
 	Type() Type
@@ -60,14 +150,49 @@ type Object interface {
 // BuiltInFunction is type for built in interpeter function
 type BuiltInFunction func(args ...Object) Object
 
+// LimitedFunction is type for built in function which checks size of its result against limits of current execution
+// before the result is allocated, execution is nil if the program is not started with limits
+type LimitedFunction func(execution *Execution, args ...Object) Object
+
+// CallFunction is type for callback which calls function object with arguments from built in function
+type CallFunction func(fn Object, args ...Object) Object
+
+// HigherOrderFunction is type for built in function which calls function objects passed to it with callback,
+// it receives current execution like LimitedFunction
+type HigherOrderFunction func(execution *Execution, call CallFunction, args ...Object) Object
+
 // BuiltIn is type for built in functionality into interpreter
 type BuiltIn struct {
-	Fn BuiltInFunction
+	Fn            BuiltInFunction
+	LimitedFn     LimitedFunction     // it is called instead of Fn if it is set
+	HigherOrderFn HigherOrderFunction // it is called instead of Fn and LimitedFn if it is set
+	Name          string              // qualified name of function, e.g. "strings.split", empty for unregistered functions
+	Params        []string            // types of parameters, e.g. "STRING" or "INTEGER|FLOAT"
+	Optional      int                 // number of trailing parameters which may be omitted
+	Variadic      bool                // last parameter may be passed any number of times
+	Doc           string
+}
+
+// Apply calls built in function with arguments within current execution, which is nil if there is no limits,
+// call is used by higher order function to call functions
+func (bi *BuiltIn) Apply(execution *Execution, call CallFunction, args ...Object) Object {
+	if bi.HigherOrderFn != nil {
+		return bi.HigherOrderFn(execution, call, args...)
+	}
+	if bi.LimitedFn != nil {
+		return bi.LimitedFn(execution, args...)
+	}
+
+	return bi.Fn(args...)
 }
 
 // Inspect returns string representation of object
 func (bi *BuiltIn) Inspect() string {
-	return fmt.Sprintf("built in function")
+	if bi.Name == "" {
+		return "built in function"
+	}
+
+	return fmt.Sprintf("built in function %s", bi.Name)
 }
 
 // Type returns type of object
@@ -90,6 +215,98 @@ func (i *Integer) Type() Type {
 	return IntegerObj
 }
 
+// BigInt is type for integers which don't fit into Integer
+type BigInt struct {
+	Value *big.Int
+}
+
+// Inspect returns string representation of object
+func (b *BigInt) Inspect() string {
+	return b.Value.String()
+}
+
+// Type returns type of object
+func (b *BigInt) Type() Type {
+	return BigIntObj
+}
+
+// Module is type for module which is imported from file or for namespace of built in functions
+type Module struct {
+	Name    string            // path of the module as it is written in import expression or name of namespace
+	Exports map[string]Object // values of exported bindings of namespace
+
+	names    []string // exported bindings of module which is imported from file
+	bindings Bindings // variables of module which is imported from file
+}
+
+// Bindings is interface for variables of module, e.g. its environment
+type Bindings interface {
+	Get(name string) (Object, bool)
+}
+
+// NewModule returns module which is imported from file, values of its exported bindings are read
+// from variables of the module, so they are current when the module changes them
+func NewModule(name string, names []string, bindings Bindings) *Module {
+	return &Module{Name: name, names: names, bindings: bindings}
+}
+
+// Export returns current value of exported binding and is the binding exported by module
+func (m *Module) Export(name string) (Object, bool) {
+	if m.bindings == nil {
+		value, ok := m.Exports[name]
+		return value, ok
+	}
+
+	for _, exported := range m.names {
+		if exported == name {
+			return m.bindings.Get(name)
+		}
+	}
+
+	return nil, false
+}
+
+// ExportNames returns sorted names of exported bindings
+func (m *Module) ExportNames() []string {
+	names := append([]string(nil), m.names...)
+	for name := range m.Exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Inspect returns string representation of object
+func (m *Module) Inspect() string {
+	return "module " + m.Name
+}
+
+// Type returns type of object
+func (m *Module) Type() Type {
+	return ModuleObj
+}
+
+// Float is type for floating-point numbers
+type Float struct {
+	Value float64
+}
+
+// Inspect returns string representation of object, it always has fraction part or exponent
+func (f *Float) Inspect() string {
+	str := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if math.IsInf(f.Value, 0) || math.IsNaN(f.Value) || strings.ContainsAny(str, ".e") {
+		return str
+	}
+
+	return str + ".0"
+}
+
+// Type returns type of object
+func (f *Float) Type() Type {
+	return FloatObj
+}
+
 // Boolean is type for boolean expressions
 type Boolean struct {
 	Value bool
@@ -148,14 +365,133 @@ func (rv *ReturnValue) Type() Type {
 	return ReturnValueObj
 }
 
+// Break is type for break statements which stop execution of loop
+type Break struct{}
+
+// Inspect returns string representation of object
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+// Type returns type of object
+func (b *Break) Type() Type {
+	return BreakObj
+}
+
+// Continue is type for continue statements which stop execution of loop iteration
+type Continue struct{}
+
+// Inspect returns string representation of object
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
+// Type returns type of object
+func (c *Continue) Type() Type {
+	return ContinueObj
+}
+
+// Range is type for range of integers from Start to End (exclusive) with Step
+type Range struct {
+	Start int64
+	End   int64
+	Step  int64
+}
+
+// Inspect returns string representation of object
+func (r *Range) Inspect() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.End, r.Step)
+}
+
+// Type returns type of object
+func (r *Range) Type() Type {
+	return RangeObj
+}
+
+// Iterator is type for state of iteration over iterable object in for loops
+type Iterator struct {
+	// Next returns index or key and element or value of the next iteration, ok is false if iteration is over
+	Next func() (key, value Object, ok bool)
+	// ByKey is true if single loop variable is bound to keys instead of values
+	ByKey bool
+}
+
+// Inspect returns string representation of object
+func (it *Iterator) Inspect() string {
+	return "iterator"
+}
+
+// Type returns type of object
+func (it *Iterator) Type() Type {
+	return IteratorObj
+}
+
 // Error is type for errors handling
 type Error struct {
 	Message string
+	Kind    string         // type of error thrown by program, empty for errors raised by interpreter
+	Fatal   bool           // error stops the program and can not be caught, programs can not throw such errors
+	Pos     token.Position // position of the node which produced error
+	Stack   []StackFrame   // calls through which error has propagated, the innermost first
 }
 
+// StackFrame is type for call of function in the stack trace of error
+type StackFrame struct {
+	Function string         // name of the called function, empty for anonymous functions
+	Pos      token.Position // position of the call expression
+}
+
+// String returns string representation of stack frame
+func (sf StackFrame) String() string {
+	name := sf.Function
+	if name == "" {
+		name = "<anonymous>"
+	}
+
+	return fmt.Sprintf("at %s (called at %s)", name, sf.Pos)
+}
+
+// maxTracebackFrames is max number of frames which are printed in traceback
+const maxTracebackFrames = 20
+
 // Inspect returns string representation of object
 func (e *Error) Inspect() string {
-	return fmt.Sprintf("Error: %s", e.Message)
+	kind := e.Kind
+	if kind == "" {
+		kind = "Error"
+	}
+
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s: %s", kind, e.Pos, e.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", kind, e.Message)
+}
+
+// Traceback returns string representation of error with its stack trace, one frame per line
+func (e *Error) Traceback() string {
+	var out bytes.Buffer
+
+	out.WriteString(e.Inspect())
+
+	// deep recursion is shortened to the innermost and the outermost frames
+	head, tail := e.Stack, []StackFrame(nil)
+	if len(e.Stack) > maxTracebackFrames {
+		head = e.Stack[:maxTracebackFrames/2]
+		tail = e.Stack[len(e.Stack)-maxTracebackFrames/2:]
+	}
+
+	for _, frame := range head {
+		out.WriteString("\n\t" + frame.String())
+	}
+	if tail != nil {
+		out.WriteString(fmt.Sprintf("\n\t... %d more frames", len(e.Stack)-maxTracebackFrames))
+	}
+	for _, frame := range tail {
+		out.WriteString("\n\t" + frame.String())
+	}
+
+	return out.String()
 }
 
 // Type returns type of object
@@ -168,6 +504,7 @@ type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Name       string
 }
 
 // Type returns type of object
@@ -194,6 +531,62 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+// Capture is type for description of variable captured by compiled function
+type Capture struct {
+	Name  string
+	Local bool // captured from locals of enclosing function, otherwise from its free variables
+	Index int
+}
+
+// CompiledFunction is type for function compiled into bytecode
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	Positions     map[int]token.Position // positions in source code by offsets of instructions
+	NumLocals     int
+	NumParameters int
+	LocalNames    []string
+	Captures      []Capture
+	Name          string
+}
+
+// Type returns type of object
+func (cf *CompiledFunction) Type() Type {
+	return CompiledFunctionObj
+}
+
+// Inspect returns string representation of object
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+// Upvalue is type for variable captured by closure
+type Upvalue struct {
+	Value  *Object // points to stack slot while variable is alive, to Closed after
+	Closed Object
+}
+
+// Close moves captured variable out of stack
+func (u *Upvalue) Close() {
+	u.Closed = *u.Value
+	u.Value = &u.Closed
+}
+
+// Closure is type for compiled function with captured variables
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []*Upvalue
+}
+
+// Type returns type of object
+func (c *Closure) Type() Type {
+	return FunctionObj
+}
+
+// Inspect returns string representation of object
+func (c *Closure) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", c)
+}
+
 // Array is type for array objects
 type Array struct {
 	Elements []Object
@@ -206,11 +599,15 @@ func (a *Array) Type() Type {
 
 // Inspect returns string representation of object
 func (a *Array) Inspect() string {
+	return inspect(a, make(map[Object]bool))
+}
+
+func (a *Array) inspect(visiting map[Object]bool) string {
 	var out bytes.Buffer
 
 	elements := []string{}
 	for _, e := range a.Elements {
-		elements = append(elements, e.Inspect())
+		elements = append(elements, inspect(e, visiting))
 	}
 
 	out.WriteString("[")
@@ -220,20 +617,202 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
-// Hashable is interface for hashable objects (such us strings, booleasn, integers)
+// inspect returns string representation of object, visiting contains arrays and hashes which are being inspected,
+// they are represented by [...] and {...} inside themselves
+func inspect(obj Object, visiting map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *Array:
+		if visiting[obj] {
+			return "[...]"
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		return obj.inspect(visiting)
+
+	case *Hash:
+		if visiting[obj] {
+			return "{...}"
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		return obj.inspect(visiting)
+
+	default:
+		return obj.Inspect()
+	}
+}
+
+// Hashable is interface for objects which can be keys of hashes (such us strings, booleans, integers),
+// different objects may have the same hash key, so keys of hashes are also compared with Equal on lookup
 type Hashable interface {
 	HashKey() HashKey
 }
 
+// Container is interface for hashable objects which contain other objects (such us arrays),
+// container can be key of hash only if all objects which it contains can be keys
+type Container interface {
+	Hashable
+	Contents() []Object
+}
+
+// HashKeyOf returns hash key of object and true if object can be key of hash
+func HashKeyOf(obj Object) (HashKey, bool) {
+	if !isHashable(obj, nil) {
+		return HashKey{}, false
+	}
+
+	return obj.(Hashable).HashKey(), true
+}
+
+// isHashable returns true if object can be key of hash, containers which contain themselves can not be keys
+func isHashable(obj Object, visiting map[Container]bool) bool {
+	container, ok := obj.(Container)
+	if !ok {
+		_, ok := obj.(Hashable)
+		return ok
+	}
+
+	if visiting == nil {
+		visiting = make(map[Container]bool)
+	}
+	if visiting[container] {
+		return false
+	}
+	visiting[container] = true
+	defer delete(visiting, container)
+
+	for _, content := range container.Contents() {
+		if !isHashable(content, visiting) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // HashPair is type for key value pair in hash map objects
 type HashPair struct {
 	Key   Object
 	Value Object
 }
 
-// Hash is type for hash map objects
+// Hash is type for hash map objects which keep insertion order of their pairs
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	buckets map[HashKey][]*HashPair // pairs with the same hash key
+	pairs   []*HashPair             // pairs in insertion order
+}
+
+// NewHash returns new empty hash
+func NewHash() *Hash {
+	return &Hash{buckets: make(map[HashKey][]*HashPair)}
+}
+
+// Get returns pair of hash with key equal to passed one and is hash contains it
+func (h *Hash) Get(key Object) (HashPair, bool) {
+	if pair := h.find(key); pair != nil {
+		return *pair, true
+	}
+
+	return HashPair{}, false
+}
+
+// Set sets value of hash by key and returns false if object can not be key of hash,
+// new pair is placed after existing ones and replaced pair keeps its place,
+// array key is copied, so changes of the array do not affect the hash
+func (h *Hash) Set(key, value Object) bool {
+	hashKey, ok := HashKeyOf(key)
+	if !ok {
+		return false
+	}
+
+	if pair := h.find(key); pair != nil {
+		pair.Value = value
+		return true
+	}
+
+	if h.buckets == nil {
+		h.buckets = make(map[HashKey][]*HashPair)
+	}
+
+	pair := &HashPair{Key: copyKey(key), Value: value}
+	h.buckets[hashKey] = append(h.buckets[hashKey], pair)
+	h.pairs = append(h.pairs, pair)
+
+	return true
+}
+
+// Delete removes pair of hash with key equal to passed one
+func (h *Hash) Delete(key Object) {
+	pair := h.find(key)
+	if pair == nil {
+		return
+	}
+
+	hashKey, _ := HashKeyOf(key)
+	h.buckets[hashKey] = removePair(h.buckets[hashKey], pair)
+	if len(h.buckets[hashKey]) == 0 {
+		delete(h.buckets, hashKey)
+	}
+	h.pairs = removePair(h.pairs, pair)
+}
+
+// Len returns number of pairs of hash
+func (h *Hash) Len() int {
+	return len(h.pairs)
+}
+
+// Pairs returns pairs of hash in insertion order
+func (h *Hash) Pairs() []HashPair {
+	pairs := make([]HashPair, len(h.pairs))
+	for i, pair := range h.pairs {
+		pairs[i] = *pair
+	}
+
+	return pairs
+}
+
+// find returns pair of hash with key equal to passed one or nil if there is no such pair
+func (h *Hash) find(key Object) *HashPair {
+	hashKey, ok := HashKeyOf(key)
+	if !ok {
+		return nil
+	}
+
+	for _, pair := range h.buckets[hashKey] {
+		if Equal(pair.Key, key) {
+			return pair
+		}
+	}
+
+	return nil
+}
+
+// removePair returns pairs without passed pair
+func removePair(pairs []*HashPair, pair *HashPair) []*HashPair {
+	for i, p := range pairs {
+		if p == pair {
+			return append(pairs[:i:i], pairs[i+1:]...)
+		}
+	}
+
+	return pairs
+}
+
+// copyKey returns deep copy of array key and other keys as is
+func copyKey(key Object) Object {
+	arr, ok := key.(*Array)
+	if !ok {
+		return key
+	}
+
+	elements := make([]Object, len(arr.Elements))
+	for i, element := range arr.Elements {
+		elements[i] = copyKey(element)
+	}
+
+	return &Array{Elements: elements}
 }
 
 // Type returns type of object
@@ -243,12 +822,16 @@ func (h *Hash) Type() Type {
 
 // Inspect returns string representation of object
 func (h *Hash) Inspect() string {
+	return inspect(h, make(map[Object]bool))
+}
+
+func (h *Hash) inspect(visiting map[Object]bool) string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Pairs() {
 		pairs = append(pairs, fmt.Sprintf("%s:%s",
-			pair.Key.Inspect(), pair.Value.Inspect()))
+			inspect(pair.Key, visiting), inspect(pair.Value, visiting)))
 	}
 
 	out.WriteString("{")
@@ -288,6 +871,70 @@ func (i *Integer) HashKey() HashKey {
 	}
 }
 
+// HashKey return HashKey object for the current object
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(b.Value.String()))
+
+	return HashKey{
+		Type:  b.Type(),
+		Value: h.Sum64(),
+	}
+}
+
+// HashKey return HashKey object for the current object,
+// float with integral value has the same key as equal integer or big integer
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && !math.IsInf(f.Value, 0) {
+		if f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+			return (&Integer{Value: int64(f.Value)}).HashKey()
+		}
+
+		value, _ := new(big.Float).SetFloat64(f.Value).Int(nil)
+		return (&BigInt{Value: value}).HashKey()
+	}
+
+	value := f.Value
+	if math.IsNaN(value) {
+		value = math.NaN()
+	}
+
+	return HashKey{
+		Type:  f.Type(),
+		Value: math.Float64bits(value),
+	}
+}
+
+// HashKey return HashKey object for the current object
+func (n *Null) HashKey() HashKey {
+	return HashKey{Type: n.Type()}
+}
+
+// HashKey return HashKey object for the current object, it combines hash keys of elements,
+// so it is meaningful only if array can be key of hash, see HashKeyOf
+func (a *Array) HashKey() HashKey {
+	h := fnv.New64a()
+	for _, element := range a.Elements {
+		var key HashKey
+		if hashable, ok := element.(Hashable); ok {
+			key = hashable.HashKey()
+		}
+
+		h.Write([]byte(key.Type))
+		binary.Write(h, binary.LittleEndian, key.Value)
+	}
+
+	return HashKey{
+		Type:  a.Type(),
+		Value: h.Sum64(),
+	}
+}
+
+// Contents returns elements of array
+func (a *Array) Contents() []Object {
+	return a.Elements
+}
+
 // HashKey return HashKey object for the current object
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
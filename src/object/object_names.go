@@ -3,6 +3,8 @@ package object
 // Literals of objects
 const (
 	IntegerObj     = "INTEGER"
+	BigIntObj      = "BIGINT"
+	FloatObj       = "FLOAT"
 	BooleanObj     = "BOOLEAN"
 	StringObj      = "STRING"
 	NullObj        = "NULL"
@@ -12,4 +14,11 @@ const (
 	BuiltInObj     = "BUILTIN"
 	ArrayObj       = "ARRAY"
 	HashObj        = "HASH"
+	BreakObj       = "BREAK"
+	ContinueObj    = "CONTINUE"
+	RangeObj       = "RANGE"
+	IteratorObj    = "ITERATOR"
+	ModuleObj      = "MODULE"
+
+	CompiledFunctionObj = "COMPILED_FUNCTION"
 )
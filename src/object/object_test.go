@@ -1,9 +1,13 @@
 package object_test
 
 import (
+	"math"
+	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/ythosa/pukiclang/src/object"
+	"github.com/ythosa/pukiclang/src/token"
 )
 
 func TestStringHashKey(t *testing.T) {
@@ -25,3 +29,309 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestFloat(t *testing.T) {
+	tests := []struct {
+		value   float64
+		inspect string
+	}{
+		{1.5, "1.5"},
+		{2, "2.0"},
+		{-3, "-3.0"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+	}
+
+	for _, tt := range tests {
+		f := &object.Float{Value: tt.value}
+		if f.Inspect() != tt.inspect {
+			t.Errorf("wrong inspect. expected=%q, got=%q", tt.inspect, f.Inspect())
+		}
+	}
+
+	if (&object.Float{Value: 2}).HashKey() != (&object.Integer{Value: 2}).HashKey() {
+		t.Errorf("integral float and equal integer have different hash keys")
+	}
+
+	huge := &object.BigInt{Value: new(big.Int).Exp(big.NewInt(10), big.NewInt(19), nil)}
+	if (&object.Float{Value: 1e19}).HashKey() != huge.HashKey() {
+		t.Errorf("integral float and equal big integer have different hash keys")
+	}
+
+	if (&object.Float{Value: 2.5}).HashKey() == (&object.Float{Value: 2}).HashKey() {
+		t.Errorf("different floats have same hash keys")
+	}
+}
+
+func TestHash(t *testing.T) {
+	var hash object.Hash
+
+	set := func(key string, value int64) {
+		hash.Set(&object.String{Value: key}, &object.Integer{Value: value})
+	}
+
+	set("c", 1)
+	set("a", 2)
+	set("b", 3)
+	set("c", 4)
+	hash.Delete(&object.String{Value: "a"})
+	hash.Delete(&object.String{Value: "missing"})
+	set("a", 5)
+
+	if hash.Len() != 3 {
+		t.Errorf("hash has wrong length. expected=3, got=%d", hash.Len())
+	}
+
+	if expected := "{c:4, b:3, a:5}"; hash.Inspect() != expected {
+		t.Errorf("wrong inspect. expected=%q, got=%q", expected, hash.Inspect())
+	}
+
+	if _, ok := hash.Get(&object.String{Value: "b"}); !ok {
+		t.Errorf("hash does not contain pair by key b")
+	}
+}
+
+func TestInspectCycles(t *testing.T) {
+	one := &object.Integer{Value: 1}
+	arr := &object.Array{Elements: []object.Object{one, one}}
+	hash := object.NewHash()
+	hash.Set(&object.String{Value: "arr"}, arr)
+	hash.Set(&object.String{Value: "self"}, hash)
+	arr.Elements[0] = arr
+	arr.Elements[1] = hash
+
+	shared := &object.Array{Elements: []object.Object{one}}
+	pair := &object.Array{Elements: []object.Object{shared, shared}}
+
+	tests := []struct {
+		obj      object.Object
+		expected string
+	}{
+		{arr, "[[...], {arr:[...], self:{...}}]"},
+		{hash, "{arr:[[...], {...}], self:{...}}"},
+		{pair, "[[1], [1]]"},
+	}
+
+	for _, tt := range tests {
+		if tt.obj.Inspect() != tt.expected {
+			t.Errorf("wrong inspect. expected=%q, got=%q", tt.expected, tt.obj.Inspect())
+		}
+	}
+}
+
+// collidingKey is type for hashable objects which all have the same hash key
+type collidingKey struct {
+	name string
+}
+
+func (k *collidingKey) Type() object.Type       { return "COLLIDING" }
+func (k *collidingKey) Inspect() string         { return k.name }
+func (k *collidingKey) HashKey() object.HashKey { return object.HashKey{Type: k.Type(), Value: 1} }
+
+func TestHashKeyCollisions(t *testing.T) {
+	a, b := &collidingKey{"a"}, &collidingKey{"b"}
+
+	hash := object.NewHash()
+	hash.Set(a, &object.Integer{Value: 1})
+	hash.Set(b, &object.Integer{Value: 2})
+
+	if hash.Len() != 2 {
+		t.Fatalf("pairs with colliding keys replace each other. got=%s", hash.Inspect())
+	}
+
+	if pair, ok := hash.Get(b); !ok || pair.Value.Inspect() != "2" {
+		t.Errorf("wrong pair for colliding key. got=%v", pair.Value)
+	}
+
+	hash.Delete(a)
+	if _, ok := hash.Get(a); ok || hash.Inspect() != "{b:2}" {
+		t.Errorf("wrong hash after deletion of colliding key. got=%s", hash.Inspect())
+	}
+}
+
+func TestHashKeyOf(t *testing.T) {
+	nested := &object.Array{Elements: []object.Object{
+		&object.Integer{Value: 1},
+		&object.Array{Elements: []object.Object{&object.String{Value: "a"}, &object.Null{}}},
+	}}
+	if _, ok := object.HashKeyOf(nested); !ok {
+		t.Errorf("array of hashable elements is not hashable")
+	}
+
+	withHash := &object.Array{Elements: []object.Object{&object.Hash{}}}
+	if _, ok := object.HashKeyOf(withHash); ok {
+		t.Errorf("array with hash is hashable")
+	}
+
+	cyclic := &object.Array{Elements: []object.Object{nil}}
+	cyclic.Elements[0] = cyclic
+	if _, ok := object.HashKeyOf(cyclic); ok {
+		t.Errorf("array which contains itself is hashable")
+	}
+
+	one := &object.Array{Elements: []object.Object{&object.Integer{Value: 1}}}
+	two := &object.Array{Elements: []object.Object{&object.Integer{Value: 2}}}
+	if one.HashKey() == two.HashKey() {
+		t.Errorf("different arrays have same hash keys")
+	}
+}
+
+func TestEqualAndCompare(t *testing.T) {
+	integers := func(values ...int64) *object.Array {
+		arr := &object.Array{}
+		for _, v := range values {
+			arr.Elements = append(arr.Elements, &object.Integer{Value: v})
+		}
+		return arr
+	}
+
+	huge := &object.BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)}
+
+	tests := []struct {
+		a, b    object.Object
+		equal   bool
+		compare int
+	}{
+		{&object.Integer{Value: 1}, &object.Float{Value: 1}, true, 0},
+		{&object.Integer{Value: 1}, huge, false, -1},
+		{&object.String{Value: "b"}, &object.String{Value: "a"}, false, 1},
+		{integers(1, 2), integers(1, 2), true, 0},
+		{integers(1, 2), integers(1, 2, 3), false, -1},
+		{integers(1, 3), integers(1, 2, 3), false, 1},
+	}
+
+	for _, tt := range tests {
+		if object.Equal(tt.a, tt.b) != tt.equal {
+			t.Errorf("Equal(%s, %s) is not %t", tt.a.Inspect(), tt.b.Inspect(), tt.equal)
+		}
+
+		result, err := object.Compare(tt.a, tt.b)
+		if err != nil || result != tt.compare {
+			t.Errorf("Compare(%s, %s) wrong. expected=%d, got=%d (%v)", tt.a.Inspect(), tt.b.Inspect(),
+				tt.compare, result, err)
+		}
+	}
+
+	// arrays which contain themselves
+	a, b := integers(1), integers(1)
+	a.Elements[0], b.Elements[0] = a, b
+	if !object.Equal(a, b) {
+		t.Errorf("equal cyclic arrays are not equal")
+	}
+	if result, err := object.Compare(a, b); err != nil || result != 0 {
+		t.Errorf("equal cyclic arrays are not equal in order. got=%d (%v)", result, err)
+	}
+
+	if _, err := object.Compare(&object.Hash{}, &object.Hash{}); err == nil || err.Error() != "HASH is not ordered" {
+		t.Errorf("wrong error for hashes. got=%v", err)
+	}
+}
+
+func TestErrorTraceback(t *testing.T) {
+	err := &object.Error{Message: "stack overflow", Pos: token.Position{Line: 1, Column: 20}}
+	for i := 0; i < 25; i++ {
+		err.Stack = append(err.Stack, object.StackFrame{
+			Function: "f",
+			Pos:      token.Position{Line: 1, Column: 20},
+		})
+	}
+	err.Stack = append(err.Stack, object.StackFrame{Pos: token.Position{Line: 2, Column: 2}})
+
+	lines := strings.Split(err.Traceback(), "\n")
+
+	if len(lines) != 22 {
+		t.Fatalf("traceback has wrong number of lines. got=%d", len(lines))
+	}
+	if lines[0] != "Error: 1:20: stack overflow" {
+		t.Errorf("lines[0] wrong. got=%q", lines[0])
+	}
+	if lines[1] != "\tat f (called at 1:20)" {
+		t.Errorf("lines[1] wrong. got=%q", lines[1])
+	}
+	if lines[11] != "\t... 6 more frames" {
+		t.Errorf("lines[11] wrong. got=%q", lines[11])
+	}
+	if lines[21] != "\tat <anonymous> (called at 2:2)" {
+		t.Errorf("lines[21] wrong. got=%q", lines[21])
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := object.NewRegistry()
+	fn := func(args ...object.Object) object.Object { return args[0] }
+
+	builtIns := []*object.BuiltIn{
+		{Name: "id", Fn: fn, Params: []string{"ANY"}},
+		{Name: "strings.split", Fn: fn, Params: []string{"STRING", "STRING"}, Optional: 1},
+		{Name: "strings.join", Fn: fn, Params: []string{"ARRAY", "STRING"}},
+		{Name: "log", Fn: fn, Params: []string{"ANY"}, Optional: 1, Variadic: true},
+	}
+	for _, builtIn := range builtIns {
+		if err := registry.Register(builtIn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	invalid := map[string]string{
+		"":              `invalid built in function name: ""`,
+		"a.b.c":         "built in function name has nested namespaces: a.b.c",
+		"bad name":      `invalid built in function name: "bad name"`,
+		"strings":       "built in function name is used by namespace: strings",
+		"id.namespaced": "namespace name is used by built in function: id",
+	}
+	for name, expected := range invalid {
+		err := registry.Register(&object.BuiltIn{Name: name, Fn: fn})
+		if err == nil || err.Error() != expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%v", name, expected, err)
+		}
+	}
+
+	if obj, ok := registry.Lookup("id"); !ok || obj.(*object.BuiltIn).Name != "id" {
+		t.Errorf("id is not found. got=%v", obj)
+	}
+
+	if _, ok := registry.Lookup("strings.split"); ok {
+		t.Errorf("qualified name is found")
+	}
+
+	namespace, ok := registry.Lookup("strings")
+	if !ok || len(namespace.(*object.Module).Exports) != 2 {
+		t.Fatalf("wrong namespace. got=%v", namespace)
+	}
+
+	signatures := []string{}
+	for _, builtIn := range registry.BuiltIns() {
+		signatures = append(signatures, builtIn.Signature())
+	}
+	expected := "id(ANY), log([ANY...]), strings.join(ARRAY, STRING), strings.split(STRING, [STRING])"
+	if strings.Join(signatures, ", ") != expected {
+		t.Errorf("wrong signatures. expected=%q, got=%q", expected, strings.Join(signatures, ", "))
+	}
+
+	split, _ := namespace.(*object.Module).Exports["split"].(*object.BuiltIn)
+	arityTests := []struct {
+		args     int
+		expected string
+	}{
+		{0, "Error: wrong number of arguments. got=0, want=1..2"},
+		{1, "STRING"},
+		{3, "Error: wrong number of arguments. got=3, want=1..2"},
+	}
+	for _, tt := range arityTests {
+		args := make([]object.Object, tt.args)
+		for i := range args {
+			args[i] = &object.String{Value: "STRING"}
+		}
+
+		result := split.Fn(args...)
+		if result.Inspect() != tt.expected {
+			t.Errorf("wrong result for %d arguments. expected=%q, got=%q", tt.args, tt.expected, result.Inspect())
+		}
+	}
+
+	registry.Remove("strings.split")
+	registry.Remove("strings.join")
+	if _, ok := registry.Lookup("strings"); ok {
+		t.Errorf("empty namespace is found")
+	}
+}
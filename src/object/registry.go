@@ -0,0 +1,201 @@
+package object
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Registry is type for set of built in functions which are available to programs,
+// function with qualified name, e.g. "strings.split", is available as member of its namespace
+type Registry struct {
+	builtIns   map[string]*BuiltIn
+	namespaces map[string]*Module
+}
+
+// NewRegistry returns new empty registry of built in functions
+func NewRegistry() *Registry {
+	return &Registry{
+		builtIns:   make(map[string]*BuiltIn),
+		namespaces: make(map[string]*Module),
+	}
+}
+
+// Register adds built in function to registry or replaces function with the same name,
+// registered function checks number of passed arguments before it is called
+func (r *Registry) Register(builtIn *BuiltIn) error {
+	namespace, name, err := splitBuiltInName(builtIn.Name)
+	if err != nil {
+		return err
+	}
+
+	if namespace == "" {
+		if _, ok := r.namespaces[name]; ok {
+			return fmt.Errorf("built in function name is used by namespace: %s", name)
+		}
+	} else if _, ok := r.builtIns[namespace]; ok {
+		return fmt.Errorf("namespace name is used by built in function: %s", namespace)
+	}
+
+	registered := *builtIn
+	fn, limitedFn, higherOrderFn := builtIn.Fn, builtIn.LimitedFn, builtIn.HigherOrderFn
+
+	switch {
+	case higherOrderFn != nil:
+		registered.HigherOrderFn = func(execution *Execution, call CallFunction, args ...Object) Object {
+			if errObj := checkArity(&registered, len(args)); errObj != nil {
+				return errObj
+			}
+			return higherOrderFn(execution, call, args...)
+		}
+	case limitedFn != nil:
+		registered.LimitedFn = func(execution *Execution, args ...Object) Object {
+			if errObj := checkArity(&registered, len(args)); errObj != nil {
+				return errObj
+			}
+			return limitedFn(execution, args...)
+		}
+	default:
+		registered.Fn = func(args ...Object) Object {
+			if errObj := checkArity(&registered, len(args)); errObj != nil {
+				return errObj
+			}
+			return fn(args...)
+		}
+	}
+	r.builtIns[builtIn.Name] = &registered
+
+	if namespace != "" {
+		module, ok := r.namespaces[namespace]
+		if !ok {
+			module = &Module{Name: namespace, Exports: make(map[string]Object)}
+			r.namespaces[namespace] = module
+		}
+
+		module.Exports[name] = &registered
+	}
+
+	return nil
+}
+
+// Remove removes built in function with qualified name from registry
+func (r *Registry) Remove(name string) {
+	if _, ok := r.builtIns[name]; !ok {
+		return
+	}
+	delete(r.builtIns, name)
+
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		namespace := r.namespaces[name[:i]]
+		delete(namespace.Exports, name[i+1:])
+
+		if len(namespace.Exports) == 0 {
+			delete(r.namespaces, name[:i])
+		}
+	}
+}
+
+// Lookup returns built in function or namespace module by name which is used in programs
+func (r *Registry) Lookup(name string) (Object, bool) {
+	if builtIn, ok := r.builtIns[name]; ok && !strings.Contains(name, ".") {
+		return builtIn, true
+	}
+
+	if namespace, ok := r.namespaces[name]; ok {
+		return namespace, true
+	}
+
+	return nil, false
+}
+
+// BuiltIns returns all registered built in functions sorted by their qualified names
+func (r *Registry) BuiltIns() []*BuiltIn {
+	builtIns := make([]*BuiltIn, 0, len(r.builtIns))
+	for _, builtIn := range r.builtIns {
+		builtIns = append(builtIns, builtIn)
+	}
+
+	sort.Slice(builtIns, func(i, j int) bool {
+		return builtIns[i].Name < builtIns[j].Name
+	})
+
+	return builtIns
+}
+
+// Arity returns number of arguments which built in function accepts, e.g. "1", "1..3" or "1+"
+func (bi *BuiltIn) Arity() string {
+	required := len(bi.Params) - bi.Optional
+
+	switch {
+	case bi.Variadic:
+		return fmt.Sprintf("%d+", required)
+	case bi.Optional > 0:
+		return fmt.Sprintf("%d..%d", required, len(bi.Params))
+	default:
+		return fmt.Sprintf("%d", required)
+	}
+}
+
+// Signature returns name of built in function with types of its parameters,
+// e.g. "range(INTEGER, [INTEGER], [INTEGER])" or "puts([ANY...])"
+func (bi *BuiltIn) Signature() string {
+	params := make([]string, len(bi.Params))
+	for i, param := range bi.Params {
+		if bi.Variadic && i == len(bi.Params)-1 {
+			param += "..."
+		}
+		if i >= len(bi.Params)-bi.Optional {
+			param = "[" + param + "]"
+		}
+
+		params[i] = param
+	}
+
+	return fmt.Sprintf("%s(%s)", bi.Name, strings.Join(params, ", "))
+}
+
+// checkArity returns error if built in function does not accept passed number of arguments
+func checkArity(builtIn *BuiltIn, numArgs int) *Error {
+	required := len(builtIn.Params) - builtIn.Optional
+	if numArgs < required || (!builtIn.Variadic && numArgs > len(builtIn.Params)) {
+		return &Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want=%s", numArgs, builtIn.Arity())}
+	}
+
+	return nil
+}
+
+// splitBuiltInName returns namespace and name of built in function by its qualified name
+func splitBuiltInName(qualified string) (string, string, error) {
+	parts := strings.Split(qualified, ".")
+	if len(parts) > 2 {
+		return "", "", fmt.Errorf("built in function name has nested namespaces: %s", qualified)
+	}
+
+	for _, part := range parts {
+		if !isIdentifier(part) {
+			return "", "", fmt.Errorf("invalid built in function name: %q", qualified)
+		}
+	}
+
+	if len(parts) == 1 {
+		return "", parts[0], nil
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// isIdentifier returns true if name can be written in program as identifier
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, ch := range name {
+		if !unicode.IsLetter(ch) && ch != '_' {
+			return false
+		}
+	}
+
+	return true
+}
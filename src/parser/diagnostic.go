@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ythosa/pukiclang/src/token"
+)
+
+// Diagnostic is type for syntax error which was found while parsing
+type Diagnostic struct {
+	Pos      token.Position
+	Message  string
+	Expected token.Type  // expected token type, empty if diagnostic is not about missing token
+	Got      token.Token // token which caused the error
+}
+
+// Error returns string representation of diagnostic in `file:line:column: message` format
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s", d.Pos, d.Message)
+}
+
+// addError records diagnostic unless the parser is already recovering from previous error
+// of the same statement, so one mistake does not produce a cascade of diagnostics
+func (p *Parser) addError(d Diagnostic) {
+	if p.recovering {
+		return
+	}
+
+	p.recovering = true
+	p.errors = append(p.errors, d)
+}
+
+// peekError records diagnostic about unexpected next token, context describes what was parsed
+func (p *Parser) peekError(t token.Type, context string) {
+	p.addError(Diagnostic{
+		Pos:      p.peekToken.Pos,
+		Message:  fmt.Sprintf("expected %s %s, got %s", t, context, describeToken(p.peekToken)),
+		Expected: t,
+		Got:      p.peekToken,
+	})
+}
+
+func (p *Parser) noPrefixParseFnError(t token.Token) {
+	p.addError(Diagnostic{
+		Pos:     t.Pos,
+		Message: fmt.Sprintf("unexpected %s, expected expression", describeToken(t)),
+		Got:     t,
+	})
+}
+
+func describeToken(t token.Token) string {
+	if t.Type == token.ILLEGAL && strings.HasPrefix(t.Literal, "/*") {
+		return "unterminated block comment"
+	}
+
+	switch t.Type {
+	case token.EOF:
+		return "end of input"
+	case token.IDENT, token.INT, token.STRING, token.ILLEGAL:
+		return fmt.Sprintf("%s %q", t.Type, t.Literal)
+	default:
+		return string(t.Type)
+	}
+}
+
+// synchronize skips tokens of the statement which failed to parse, so parsing continues
+// from the first token of the next statement or from the closing brace of the current block
+func (p *Parser) synchronize(start token.Token) {
+	p.recovering = false
+	depth := 0
+
+	for !p.curTokenIs(token.EOF) {
+		switch p.curToken.Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			depth++
+
+		case token.RPAREN, token.RBRACKET:
+			if depth > 0 {
+				depth--
+			}
+
+		case token.RBRACE:
+			if depth == 0 && p.blockDepth > 0 {
+				return
+			}
+			if depth > 0 {
+				depth--
+			}
+
+		case token.SEMICOLON:
+			if depth == 0 {
+				p.nextToken()
+				return
+			}
+
+		case token.LET, token.RETURN, token.THROW, token.BREAK, token.CONTINUE, token.EXPORT:
+			if depth == 0 && p.curToken.Pos.Offset != start.Pos.Offset && !p.isExportedLet(start) {
+				return
+			}
+		}
+
+		p.nextToken()
+	}
+}
+
+// isExportedLet returns true if the current token is let of export statement which starts with start token,
+// so the let is not the next statement
+func (p *Parser) isExportedLet(start token.Token) bool {
+	return start.Type == token.EXPORT && p.curTokenIs(token.LET) && p.prevToken.Pos.Offset == start.Pos.Offset
+}
@@ -2,7 +2,9 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 
 	"github.com/ythosa/pukiclang/src/ast"
 	"github.com/ythosa/pukiclang/src/lexer"
@@ -13,13 +15,19 @@ import (
 type Parser struct {
 	l *lexer.Lexer
 
+	prevToken token.Token
 	curToken  token.Token
 	peekToken token.Token
 
 	prefixParseFns map[token.Type]prefixParseFn
 	infixParseFns  map[token.Type]infixParseFn
 
-	errors []string
+	comments []*ast.Comment // comments which lexer emitted
+
+	errors     []Diagnostic
+	recovering bool // true after error until the parser is synchronized on the next statement
+	blockDepth int  // number of block statements which are being parsed
+	loopDepth  int  // number of loops of the current function which are being parsed
 }
 
 type (
@@ -31,12 +39,13 @@ type (
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []Diagnostic{},
 	}
 
 	p.prefixParseFns = make(map[token.Type]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
@@ -47,20 +56,35 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.WHILE, p.parseWhileExpression)
+	p.registerPrefix(token.FOR, p.parseForExpression)
+	p.registerPrefix(token.IMPORT, p.parseImportExpression)
 
 	p.infixParseFns = make(map[token.Type]infixParseFn)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOTEQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LTEQ, p.parseInfixExpression)
 	p.registerInfix(token.GTEQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUSASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUSASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISKASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASHASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PERCENTASSIGN, p.parseAssignExpression)
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -69,20 +93,21 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
-// Errors return errors while parsing
-func (p *Parser) Errors() []string {
+// Errors return diagnostics of syntax errors found while parsing
+func (p *Parser) Errors() []Diagnostic {
 	return p.errors
 }
 
-func (p *Parser) peekError(t token.Type) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
-}
-
+// nextToken advances tokens, comments are collected instead of being parsed
 func (p *Parser) nextToken() {
+	p.prevToken = p.curToken
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	for p.peekToken.Type == token.COMMENT {
+		p.comments = append(p.comments, &ast.Comment{Token: p.peekToken})
+		p.peekToken = p.l.NextToken()
+	}
 }
 
 func (p *Parser) registerPrefix(tokenType token.Type, fn prefixParseFn) {
@@ -93,20 +118,34 @@ func (p *Parser) registerInfix(tokenType token.Type, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
-// ParseProgram is parsing sequense of tokens and returns AST tree of program
+// ParseProgram is parsing sequense of tokens and returns AST tree of program,
+// if program has syntax errors the tree contains bad nodes in place of broken parts
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
-	program.Statements = []ast.Statement{}
+	program.Statements = p.parseStatements(token.EOF)
+	program.Comments = p.comments
+
+	return program
+}
+
+// parseStatements parses statements until the end token, recovering after broken statements
+func (p *Parser) parseStatements(end token.Type) []ast.Statement {
+	statements := []ast.Statement{}
+
+	for !p.curTokenIs(end) && !p.curTokenIs(token.EOF) {
+		start := p.curToken
+
+		statements = append(statements, p.parseStatement())
 
-	for p.curToken.Type != token.EOF {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			program.Statements = append(program.Statements, stmt)
+		if p.recovering {
+			p.synchronize(start)
+			continue
 		}
+
 		p.nextToken()
 	}
 
-	return program
+	return statements
 }
 
 func (p *Parser) parseStatement() ast.Statement {
@@ -115,6 +154,12 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControlStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -128,9 +173,10 @@ func (p *Parser) peekTokenIs(t token.Type) bool {
 	return p.peekToken.Type == t
 }
 
-func (p *Parser) expectPeek(t token.Type) bool {
+// expectPeek moves to the next token if it has passed type, context is used in error message
+func (p *Parser) expectPeek(t token.Type, context string) bool {
 	if !p.peekTokenIs(t) {
-		p.peekError(t)
+		p.peekError(t, context)
 		return false
 	}
 
@@ -138,11 +184,11 @@ func (p *Parser) expectPeek(t token.Type) bool {
 	return true
 }
 
-func (p *Parser) parseLetStatement() *ast.LetStatement {
+func (p *Parser) parseLetStatement() ast.Statement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENT) {
-		return nil
+	if !p.expectPeek(token.IDENT, "after let") {
+		return &ast.BadStatement{Token: stmt.Token}
 	}
 
 	stmt.Name = &ast.Identifier{
@@ -150,14 +196,19 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 		Value: p.curToken.Literal,
 	}
 
-	if !p.expectPeek(token.ASSIGN) {
-		return nil
+	if !p.expectPeek(token.ASSIGN, "after name in let statement") {
+		stmt.Value = &ast.BadExpression{Token: p.peekToken}
+		return stmt
 	}
 
 	p.nextToken()
 
 	stmt.Value = p.parseExpression(LOWEST)
 
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		fl.Name = stmt.Name.Value
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -165,6 +216,68 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
+func (p *Parser) parseThrowStatement() ast.Statement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	var stmt ast.Statement
+	if p.curTokenIs(token.BREAK) {
+		stmt = &ast.BreakStatement{Token: p.curToken}
+	} else {
+		stmt = &ast.ContinueStatement{Token: p.curToken}
+	}
+
+	// statement is well-formed, so parser does not need to recover after the error
+	if p.loopDepth == 0 && !p.recovering {
+		p.errors = append(p.errors, Diagnostic{
+			Pos:     p.curToken.Pos,
+			Message: fmt.Sprintf("%s outside of loop", p.curToken.Literal),
+			Got:     p.curToken,
+		})
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseExportStatement() ast.Statement {
+	exportToken := p.curToken
+
+	// statement is well-formed, so parser does not need to recover after the error
+	if p.blockDepth > 0 && !p.recovering {
+		p.errors = append(p.errors, Diagnostic{
+			Pos:     p.curToken.Pos,
+			Message: "export outside of top level",
+			Got:     p.curToken,
+		})
+	}
+
+	if !p.expectPeek(token.LET, "after export") {
+		return &ast.BadStatement{Token: exportToken}
+	}
+
+	stmt, ok := p.parseLetStatement().(*ast.LetStatement)
+	if !ok {
+		return &ast.BadStatement{Token: exportToken}
+	}
+
+	return &ast.ExportStatement{Token: exportToken, Statement: stmt}
+}
+
 func (p *Parser) parseReturnStatement() ast.Statement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
@@ -182,12 +295,13 @@ func (p *Parser) parseReturnStatement() ast.Statement {
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
-	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Elements = p.parseExpressionList(token.RBRACKET, "to close array literal")
 
 	return array
 }
 
-func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
+// parseExpressionList parses comma separated expressions, on error it returns already parsed ones
+func (p *Parser) parseExpressionList(end token.Type, context string) []ast.Expression {
 	var list []ast.Expression
 
 	if p.peekTokenIs(end) {
@@ -204,9 +318,7 @@ func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
 		list = append(list, p.parseExpression(LOWEST))
 	}
 
-	if !p.expectPeek(end) {
-		return nil
-	}
+	p.expectPeek(end, context)
 
 	return list
 }
@@ -215,16 +327,22 @@ func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // =, +=, -=, *=, /=, %=
+	OR          // ||
+	AND         // &&
 	EQUALS      // == or !=
 	LESSGREATER // >, <, >=, <=
 	SUM         // +
-	PRODUCT     // *
+	PRODUCT     // *, /, %
 	PREFIX      // -X or !X
+	POWER       // **
 	CALL        // superFunc(X)
-	INDEX       // array[index]
+	INDEX       // array[index] or module.name
 )
 
 var precedences = map[token.Type]int{
+	token.OR:       OR,
+	token.AND:      AND,
 	token.EQ:       EQUALS,
 	token.NOTEQ:    EQUALS,
 	token.GT:       LESSGREATER,
@@ -235,8 +353,18 @@ var precedences = map[token.Type]int{
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.PERCENT:  PRODUCT,
+	token.POWER:    POWER,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
+
+	token.ASSIGN:         ASSIGN,
+	token.PLUSASSIGN:     ASSIGN,
+	token.MINUSASSIGN:    ASSIGN,
+	token.ASTERISKASSIGN: ASSIGN,
+	token.SLASHASSIGN:    ASSIGN,
+	token.PERCENTASSIGN:  ASSIGN,
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -269,19 +397,14 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	return stmt
 }
 
-func (p *Parser) noPrefixParseFnError(t token.Type) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
-}
-
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	//defer untrace(trace("parseExpression"))
 
 	prefix := p.prefixParseFns[p.curToken.Type]
 
 	if prefix == nil {
-		p.noPrefixParseFnError(p.curToken.Type)
-		return nil
+		p.noPrefixParseFnError(p.curToken)
+		return &ast.BadExpression{Token: p.curToken}
 	}
 	leftExp := prefix()
 
@@ -308,8 +431,25 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	p.nextToken()
 	exp.Index = p.parseExpression(LOWEST)
 
-	if !p.expectPeek(token.RBRACKET) {
-		return nil
+	p.expectPeek(token.RBRACKET, "to close index expression")
+
+	return exp
+}
+
+// parseMemberExpression parses `object.name` as index expression `object["name"]`
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{
+		Token: p.curToken,
+		Left:  left,
+	}
+
+	if !p.expectPeek(token.IDENT, "after '.'") {
+		return &ast.BadExpression{Token: exp.Token}
+	}
+
+	exp.Index = &ast.StringLiteral{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
 	}
 
 	return exp
@@ -339,12 +479,46 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	if p.curTokenIs(token.POWER) {
+		// power is right associative, so 2 ** 3 ** 2 is 2 ** (3 ** 2)
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
 	return expression
 }
 
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Target:   target,
+		Operator: strings.TrimSuffix(p.curToken.Literal, "="),
+	}
+
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		p.addError(Diagnostic{
+			Pos:     target.Pos(),
+			Message: fmt.Sprintf("cannot assign to %s", target.String()),
+			Got:     p.curToken,
+		})
+		return &ast.BadExpression{Token: expression.Token}
+	}
+
+	// assignment is right associative, so a = b = c is a = (b = c)
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	ident, isIdent := target.(*ast.Identifier)
+	if fl, ok := expression.Value.(*ast.FunctionLiteral); ok && isIdent && fl.Name == "" {
+		fl.Name = ident.Value
+	}
+
+	return expression
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{
 		Token: p.curToken,
@@ -359,9 +533,34 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
-		return nil
+		if bigValue, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+			return &ast.BigIntegerLiteral{Token: p.curToken, Value: bigValue}
+		}
+
+		p.addError(Diagnostic{
+			Pos:     p.curToken.Pos,
+			Message: fmt.Sprintf("could not parse %q as integer", p.curToken.Literal),
+			Got:     p.curToken,
+		})
+		return &ast.BadExpression{Token: p.curToken}
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.addError(Diagnostic{
+			Pos:     p.curToken.Pos,
+			Message: fmt.Sprintf("could not parse %q as float", p.curToken.Literal),
+			Got:     p.curToken,
+		})
+		return &ast.BadExpression{Token: p.curToken}
 	}
 
 	lit.Value = value
@@ -377,6 +576,18 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	}
 }
 
+func (p *Parser) parseImportExpression() ast.Expression {
+	exp := &ast.ImportExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.STRING, "after import") {
+		return &ast.BadExpression{Token: exp.Token}
+	}
+
+	exp.Path = p.curToken.Literal
+
+	return exp
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{
 		Token: p.curToken,
@@ -389,9 +600,7 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 
 	exp := p.parseExpression(LOWEST)
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
-	}
+	p.expectPeek(token.RPAREN, "to close grouped expression")
 
 	return exp
 }
@@ -399,18 +608,18 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 func (p *Parser) parseIfExpression() ast.Expression {
 	expression := &ast.IfExpression{Token: p.curToken}
 
-	if !p.expectPeek(token.LPAREN) {
-		return nil
+	if !p.expectPeek(token.LPAREN, "after if") {
+		return &ast.BadExpression{Token: expression.Token}
 	}
 
 	p.nextToken()
 	expression.Condition = p.parseExpression(LOWEST)
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+	if !p.expectPeek(token.RPAREN, "after if condition") {
+		return &ast.BadExpression{Token: expression.Token}
 	}
-	if !p.expectPeek(token.LBRACE) {
-		return nil
+	if !p.expectPeek(token.LBRACE, "to open if body") {
+		return &ast.BadExpression{Token: expression.Token}
 	}
 
 	expression.Consequence = p.parseBlockStatement()
@@ -420,8 +629,8 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	}
 
 	p.nextToken()
-	if !p.expectPeek(token.LBRACE) {
-		return nil
+	if !p.expectPeek(token.LBRACE, "to open else body") {
+		return &ast.BadExpression{Token: expression.Token}
 	}
 
 	expression.Alternative = p.parseBlockStatement()
@@ -429,19 +638,145 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
-func (p *Parser) parseBlockStatement() *ast.BlockStatement {
-	block := &ast.BlockStatement{Token: p.curToken}
-	block.Statements = []ast.Statement{}
+func (p *Parser) parseWhileExpression() ast.Expression {
+	expression := &ast.WhileExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN, "after while") {
+		return &ast.BadExpression{Token: expression.Token}
+	}
+
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN, "after while condition") {
+		return &ast.BadExpression{Token: expression.Token}
+	}
+	if !p.expectPeek(token.LBRACE, "to open while body") {
+		return &ast.BadExpression{Token: expression.Token}
+	}
+
+	expression.Body = p.parseLoopBody()
+
+	return expression
+}
+
+func (p *Parser) parseForExpression() ast.Expression {
+	expression := &ast.ForExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN, "after for") {
+		return &ast.BadExpression{Token: expression.Token}
+	}
+	if !p.expectPeek(token.IDENT, "as loop variable") {
+		return &ast.BadExpression{Token: expression.Token}
+	}
+
+	expression.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT, "as loop variable") {
+			return &ast.BadExpression{Token: expression.Token}
+		}
+
+		expression.Key = expression.Value
+		expression.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.IN, "after loop variables") {
+		return &ast.BadExpression{Token: expression.Token}
+	}
 
 	p.nextToken()
+	expression.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN, "after iterable") {
+		return &ast.BadExpression{Token: expression.Token}
+	}
+	if !p.expectPeek(token.LBRACE, "to open for body") {
+		return &ast.BadExpression{Token: expression.Token}
+	}
+
+	expression.Body = p.parseLoopBody()
 
-	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			block.Statements = append(block.Statements, stmt)
+	return expression
+}
+
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+
+	return p.parseBlockStatement()
+}
+
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE, "to open try body") {
+		return &ast.BadExpression{Token: expression.Token}
+	}
+
+	expression.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LPAREN, "after catch") {
+			return &ast.BadExpression{Token: expression.Token}
+		}
+		if !p.expectPeek(token.IDENT, "as catch parameter") {
+			return &ast.BadExpression{Token: expression.Token}
+		}
+
+		expression.CatchParam = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+		if !p.expectPeek(token.RPAREN, "after catch parameter") {
+			return &ast.BadExpression{Token: expression.Token}
 		}
+		if !p.expectPeek(token.LBRACE, "to open catch body") {
+			return &ast.BadExpression{Token: expression.Token}
+		}
+
+		expression.Catch = p.parseBlockStatement()
+	}
 
+	if p.peekTokenIs(token.FINALLY) {
 		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE, "to open finally body") {
+			return &ast.BadExpression{Token: expression.Token}
+		}
+
+		expression.Finally = p.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && expression.Finally == nil {
+		p.addError(Diagnostic{
+			Pos:     p.peekToken.Pos,
+			Message: fmt.Sprintf("expected catch or finally after try body, got %s", describeToken(p.peekToken)),
+			Got:     p.peekToken,
+		})
+		return &ast.BadExpression{Token: expression.Token}
+	}
+
+	return expression
+}
+
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	p.blockDepth++
+	block.Statements = p.parseStatements(token.RBRACE)
+	p.blockDepth--
+
+	if !p.curTokenIs(token.RBRACE) {
+		p.addError(Diagnostic{
+			Pos:      p.curToken.Pos,
+			Message:  fmt.Sprintf("expected } to close block opened at %s, got %s", block.Pos(), describeToken(p.curToken)),
+			Expected: token.RBRACE,
+			Got:      p.curToken,
+		})
 	}
 
 	return block
@@ -450,17 +785,21 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 func (p *Parser) parseFunctionExpression() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 
-	if !p.expectPeek(token.LPAREN) {
-		return nil
+	if !p.expectPeek(token.LPAREN, "after fn") {
+		return &ast.BadExpression{Token: lit.Token}
 	}
 
 	lit.Parameters = p.parseFunctionParameters()
 
-	if !p.expectPeek(token.LBRACE) {
-		return nil
+	if !p.expectPeek(token.LBRACE, "to open function body") {
+		return &ast.BadExpression{Token: lit.Token}
 	}
 
+	// loops outside of function can not be controlled from its body
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
 	lit.Body = p.parseBlockStatement()
+	p.loopDepth = loopDepth
 
 	return lit
 }
@@ -473,7 +812,9 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		return identifiers
 	}
 
-	p.nextToken()
+	if !p.expectPeek(token.IDENT, "as function parameter") {
+		return identifiers
+	}
 
 	ident := &ast.Identifier{
 		Token: p.curToken,
@@ -483,7 +824,10 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
+		if !p.expectPeek(token.IDENT, "as function parameter") {
+			return identifiers
+		}
+
 		ident := &ast.Identifier{
 			Token: p.curToken,
 			Value: p.curToken.Literal,
@@ -491,9 +835,7 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		identifiers = append(identifiers, ident)
 	}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
-	}
+	p.expectPeek(token.RPAREN, "to close function parameters")
 
 	return identifiers
 }
@@ -504,35 +846,34 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 		Function: function,
 	}
 
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Arguments = p.parseExpressionList(token.RPAREN, "to close call arguments")
 
 	return exp
 }
 
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.curToken}
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
 		key := p.parseExpression(LOWEST)
 
-		if !p.expectPeek(token.COLON) {
-			return nil
+		if !p.expectPeek(token.COLON, "after hash key") {
+			return &ast.BadExpression{Token: hash.Token}
 		}
 
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashLiteralPair{Key: key, Value: value})
 
-		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
-			return nil
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA, "between hash pairs") {
+			return &ast.BadExpression{Token: hash.Token}
 		}
 	}
 
-	if !p.expectPeek(token.RBRACE) {
-		return nil
+	if !p.expectPeek(token.RBRACE, "to close hash literal") {
+		return &ast.BadExpression{Token: hash.Token}
 	}
 
 	return hash
@@ -7,6 +7,7 @@ import (
 	"github.com/ythosa/pukiclang/src/ast"
 	"github.com/ythosa/pukiclang/src/lexer"
 	"github.com/ythosa/pukiclang/src/parser"
+	"github.com/ythosa/pukiclang/src/token"
 )
 
 func TestLetStatement(t *testing.T) {
@@ -51,8 +52,8 @@ func checkParserErrors(t *testing.T, p *parser.Parser) {
 	}
 
 	t.Errorf("parser has %d errors", len(errors))
-	for _, msg := range errors {
-		t.Errorf("parser error: %q", msg)
+	for _, d := range errors {
+		t.Errorf("parser error: %q", d.Error())
 	}
 	t.FailNow()
 }
@@ -186,6 +187,35 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5;", 1.5},
+		{".25;", 0.25},
+		{"1e9;", 1e9},
+		{"2.5e-3;", 2.5e-3},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input        string
@@ -433,6 +463,38 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"-a * b",
 			"((-a) * b)",
 		},
+		{
+			"a + b % c",
+			"(a + (b % c))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a == b && !c || d < e",
+			"(((a == b) && (!c)) || (d < e))",
+		},
+		{
+			"x = a || b",
+			"(x = (a || b))",
+		},
+		{
+			"a ** b ** c",
+			"(a ** (b ** c))",
+		},
+		{
+			"-a ** b",
+			"(-(a ** b))",
+		},
+		{
+			"a * b ** -c",
+			"(a * (b ** (-c)))",
+		},
+		{
+			"x %= a ** 2",
+			"(x %= (a ** 2))",
+		},
 		{
 			"!-a",
 			"(!(-a))",
@@ -533,6 +595,22 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"x = a + b * c",
+			"(x = (a + (b * c)))",
+		},
+		{
+			"a = b = c",
+			"(a = (b = c))",
+		},
+		{
+			"a[i + 1] += b == c",
+			"((a[(i + 1)]) += (b == c))",
+		},
+		{
+			"x -= 1; y *= 2; z /= 3",
+			"(x -= 1)(y *= 2)(z /= 3)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -656,6 +734,121 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestTryExpression(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedParam string
+		hasCatch      bool
+		hasFinally    bool
+	}{
+		{`try { x } catch (e) { y }`, "e", true, false},
+		{`try { x } finally { y }`, "", false, true},
+		{`try { x } catch (err) { y } finally { z }`, "err", true, true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		exp, ok := stmt.Expression.(*ast.TryExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.TryExpression. got=%T", stmt.Expression)
+		}
+
+		if len(exp.Block.Statements) != 1 {
+			t.Errorf("try block is not 1 statement. got=%d", len(exp.Block.Statements))
+		}
+
+		if (exp.Catch != nil) != tt.hasCatch {
+			t.Errorf("exp.Catch wrong. expected catch=%t, got=%+v", tt.hasCatch, exp.Catch)
+		}
+		if tt.hasCatch && exp.CatchParam.Value != tt.expectedParam {
+			t.Errorf("exp.CatchParam wrong. expected=%q, got=%q", tt.expectedParam, exp.CatchParam.Value)
+		}
+
+		if (exp.Finally != nil) != tt.hasFinally {
+			t.Errorf("exp.Finally wrong. expected finally=%t, got=%+v", tt.hasFinally, exp.Finally)
+		}
+	}
+}
+
+func TestLoopParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < 10) { break; }", "while(x < 10) break;"},
+		{"for (x in xs) { continue; }", "for(x in xs) continue;"},
+		{"for (k, v in h) { puts(k, v) }", "for(k, v in h) puts(k, v)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestThrowStatement(t *testing.T) {
+	l := lexer.New(`throw "boom";`)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ThrowStatement. got=%T", program.Statements[0])
+	}
+
+	if stmt.String() != `throw boom;` {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestModuleParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let m = import "lib/math";`, `let m = import "lib/math";`},
+		{`export let pi = 3;`, `export let pi = 3;`},
+		{`m.name`, `(m[name])`},
+		{`m.f(1).x[0]`, `(((m[f])(1)[x])[0])`},
+		{`h.count += 1`, `((h[count]) += 1)`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -910,20 +1103,30 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
 
-	expected := map[string]int64{
-		"one":   1,
-		"two":   2,
-		"three": 3,
+	expected := []struct {
+		key   string
+		value int64
+	}{
+		{"one", 1},
+		{"two", 2},
+		{"three", 3},
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for i, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
+			continue
 		}
 
-		expectedValue := expected[literal.String()]
-		testIntegerLiteral(t, value, expectedValue)
+		if literal.String() != expected[i].key {
+			t.Errorf("pair %d has wrong key. want=%q, got=%q", i, expected[i].key, literal.String())
+		}
+		testIntegerLiteral(t, pair.Value, expected[i].value)
+	}
+
+	if hash.String() != "{one:1, two:2, three:3}" {
+		t.Errorf("hash.String() wrong. got=%q", hash.String())
 	}
 
 }
@@ -977,10 +1180,10 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 			continue
 		}
 
@@ -990,6 +1193,220 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 			continue
 		}
 
-		testFunc(value)
+		testFunc(pair.Value)
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let x 5;", `1:7: expected = after name in let statement, got INT "5"`},
+		{"let x = 5;\n  let = 10;", "2:7: expected IDENT after let, got ="},
+		{"fn(x) { x }\n)", "2:1: unexpected ), expected expression"},
+		{"add(1, 2", "1:9: expected ) to close call arguments, got end of input"},
+		{"if (x) { x", "1:11: expected } to close block opened at 1:8, got end of input"},
+		{"fn(x, 1) {}", `1:7: expected IDENT as function parameter, got INT "1"`},
+		{"break;", "1:1: break outside of loop"},
+		{"1 = 2", "1:1: cannot assign to 1"},
+		{"f() = 2", "1:2: cannot assign to f()"},
+		{"while (true) { fn() { continue } }", "1:23: continue outside of loop"},
+		{"for (x y) { x }", `1:8: expected IN after loop variables, got IDENT "y"`},
+		{"try { 1 } 2", `1:11: expected catch or finally after try body, got INT "2"`},
+		{"try { 1 } catch { 2 }", "1:17: expected ( after catch, got {"},
+		{"import util", `1:8: expected STRING after import, got IDENT "util"`},
+		{"export x = 1", `1:8: expected LET after export, got IDENT "x"`},
+		{"if (x) { export let y = 1 }", "1:10: export outside of top level"},
+		{"m.[1]", "1:3: expected IDENT after '.', got ["},
+		{"let x = 1;\n/* comment", "2:1: unexpected unterminated block comment, expected expression"},
+		{"add(1, /* 2)", "1:8: unexpected unterminated block comment, expected expression"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("parser has no errors for %q", tt.input)
+		}
+
+		if errors[0].Error() != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0].Error())
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// adds numbers
+let add = fn(a, b) { a /* left */ + b };
+add(1, 2) // call`
+
+	l := lexer.New(input)
+	l.SetEmitComments(true)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.String() != "let add = fn(a, b)(a + b);add(1, 2)" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+
+	expected := []struct {
+		literal string
+		pos     string
+	}{
+		{"// adds numbers", "1:1"},
+		{"/* left */", "2:24"},
+		{"// call", "3:11"},
+	}
+
+	if len(program.Comments) != len(expected) {
+		t.Fatalf("program.Comments has wrong length. got=%d", len(program.Comments))
+	}
+
+	for i, comment := range program.Comments {
+		if comment.String() != expected[i].literal || comment.Pos().String() != expected[i].pos {
+			t.Errorf("comments[%d] wrong. expected=%q at %s, got=%q at %s",
+				i, expected[i].literal, expected[i].pos, comment.String(), comment.Pos())
+		}
+	}
+}
+
+func TestParserRecovery(t *testing.T) {
+	input := `let x 5;
+let y = 10;
+let = 3;
+let add = fn(a, b) {
+	let z = );
+	a + b
+};
+add(x, y);`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	expectedErrors := []string{
+		`1:7: expected = after name in let statement, got INT "5"`,
+		"3:5: expected IDENT after let, got =",
+		"5:10: unexpected ), expected expression",
+	}
+
+	errors := p.Errors()
+	if len(errors) != len(expectedErrors) {
+		for _, d := range errors {
+			t.Errorf("parser error: %q", d.Error())
+		}
+		t.Fatalf("wrong number of errors. expected=%d, got=%d", len(expectedErrors), len(errors))
+	}
+
+	for i, expected := range expectedErrors {
+		if errors[i].Error() != expected {
+			t.Errorf("errors[%d] wrong. expected=%q, got=%q", i, expected, errors[i].Error())
+		}
+	}
+
+	expectedStatements := []string{
+		"let x = <bad expression>;",
+		"let y = 10;",
+		"<bad statement>",
+		"let add = fn(a, b)let z = <bad expression>;(a + b);",
+		"add(x, y)",
+	}
+
+	if len(program.Statements) != len(expectedStatements) {
+		t.Fatalf("program.Statements has wrong length. expected=%d, got=%d",
+			len(expectedStatements), len(program.Statements))
+	}
+
+	for i, expected := range expectedStatements {
+		if program.Statements[i].String() != expected {
+			t.Errorf("statement %d wrong. expected=%q, got=%q", i, expected, program.Statements[i].String())
+		}
+	}
+}
+
+func TestExportRecovery(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+	}{
+		{"export let", []string{"1:11: expected IDENT after let, got end of input"}},
+		{"if (true) { export let }", []string{
+			"1:13: export outside of top level",
+			"1:24: expected IDENT after let, got }",
+		}},
+		{"export let = 5; let y = 1; let", []string{
+			"1:12: expected IDENT after let, got =",
+			"1:31: expected IDENT after let, got end of input",
+		}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q. expected=%d, got=%v", tt.input, len(tt.expectedErrors), errors)
+			continue
+		}
+
+		for i, expected := range tt.expectedErrors {
+			if errors[i].Error() != expected {
+				t.Errorf("errors[%d] wrong for %q. expected=%q, got=%q", i, tt.input, expected, errors[i].Error())
+			}
+		}
+	}
+}
+
+func TestDiagnosticTokens(t *testing.T) {
+	l := lexer.New("let x 5;")
+	p := parser.New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("parser has wrong number of errors. got=%d", len(errors))
+	}
+
+	d := errors[0]
+	if d.Expected != token.ASSIGN {
+		t.Errorf("d.Expected wrong. expected=%q, got=%q", token.ASSIGN, d.Expected)
+	}
+	if d.Got.Type != token.INT || d.Got.Literal != "5" {
+		t.Errorf("d.Got wrong. got=%+v", d.Got)
+	}
+	if d.Pos.Line != 1 || d.Pos.Column != 7 {
+		t.Errorf("d.Pos wrong. got=%s", d.Pos)
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	input := `let add = fn(a, b) {
+	a + b
+};`
+
+	l := lexer.NewFile("main.puki", input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	if stmt.Pos().String() != "main.puki:1:1" {
+		t.Errorf("stmt.Pos() wrong. got=%s", stmt.Pos())
+	}
+
+	function := stmt.Value.(*ast.FunctionLiteral)
+	if function.Pos().String() != "main.puki:1:11" {
+		t.Errorf("function.Pos() wrong. got=%s", function.Pos())
+	}
+
+	body := function.Body.Statements[0].(*ast.ExpressionStatement)
+	if body.Expression.Pos().String() != "main.puki:2:4" {
+		t.Errorf("body.Expression.Pos() wrong. got=%s", body.Expression.Pos())
 	}
 }
@@ -0,0 +1,436 @@
+package pukiclang
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+
+	"github.com/ythosa/pukiclang/src/evaluator"
+	"github.com/ythosa/pukiclang/src/object"
+)
+
+var (
+	emptyInterfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+	errorType          = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// ToObject converts Go value to object. Supported values are nil, booleans, numbers, *big.Int, strings,
+// slices, arrays, maps with keys of supported types, functions and objects themselves.
+// Go functions become built in functions, see Interpreter.Register
+func ToObject(value interface{}) (object.Object, error) {
+	switch value := value.(type) {
+	case nil:
+		return evaluator.NULL, nil
+	case object.Object:
+		return value, nil
+	case *big.Int:
+		if value.IsInt64() {
+			return &object.Integer{Value: value.Int64()}, nil
+		}
+		return &object.BigInt{Value: new(big.Int).Set(value)}, nil
+	case func(args ...object.Object) object.Object:
+		return &object.BuiltIn{Fn: value}, nil
+	case object.BuiltInFunction:
+		return &object.BuiltIn{Fn: value}, nil
+	}
+
+	return valueToObject(reflect.ValueOf(value))
+}
+
+func valueToObject(v reflect.Value) (object.Object, error) {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return evaluator.TRUE, nil
+		}
+		return evaluator.FALSE, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return &object.BigInt{Value: new(big.Int).SetUint64(v.Uint())}, nil
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return evaluator.NULL, nil
+		}
+
+		elements := make([]object.Object, v.Len())
+		for i := range elements {
+			element, err := ToObject(v.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+
+		return &object.Array{Elements: elements}, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+
+		pairs := make([]object.HashPair, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keyObject, err := ToObject(key.Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := object.HashKeyOf(keyObject); !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", keyObject.Type())
+			}
+
+			value, err := ToObject(v.MapIndex(key).Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			pairs = append(pairs, object.HashPair{Key: keyObject, Value: value})
+		}
+
+		// Go maps are not ordered, so pairs are inserted in order of keys to make hash deterministic
+		sort.Slice(pairs, func(i, j int) bool {
+			if pairs[i].Key.Type() != pairs[j].Key.Type() {
+				return pairs[i].Key.Type() < pairs[j].Key.Type()
+			}
+
+			return pairs[i].Key.Inspect() < pairs[j].Key.Inspect()
+		})
+
+		hash := object.NewHash()
+		for _, pair := range pairs {
+			hash.Set(pair.Key, pair.Value)
+		}
+
+		return hash, nil
+
+	case reflect.Func:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return functionToBuiltIn(v)
+
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return ToObject(v.Elem().Interface())
+
+	default:
+		return nil, fmt.Errorf("cannot convert %s to object", v.Type())
+	}
+}
+
+// ToGo converts object to Go value. Integers become int64, big integers *big.Int, floats float64,
+// arrays []interface{}, hashes and modules map[string]interface{} (keys which are not strings are
+// represented by their Inspect), functions func(args ...interface{}) (interface{}, error) and null nil,
+// error is returned for containers which contain themselves
+func ToGo(obj object.Object) (interface{}, error) {
+	return toGo(obj, make(map[object.Object]bool))
+}
+
+// toGo converts object to Go value, visiting contains containers which are being converted
+func toGo(obj object.Object, visiting map[object.Object]bool) (interface{}, error) {
+	switch obj.(type) {
+	case *object.Array, *object.Hash, *object.Module:
+		if visiting[obj] {
+			return nil, fmt.Errorf("cannot convert %s which contains itself to Go value", obj.Type())
+		}
+
+		visiting[obj] = true
+		defer delete(visiting, obj)
+	}
+
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+		return nil, nil
+
+	case *object.Boolean:
+		return obj.Value, nil
+
+	case *object.Integer:
+		return obj.Value, nil
+
+	case *object.BigInt:
+		return new(big.Int).Set(obj.Value), nil
+
+	case *object.Float:
+		return obj.Value, nil
+
+	case *object.String:
+		return obj.Value, nil
+
+	case *object.Array:
+		values := make([]interface{}, len(obj.Elements))
+		for i, element := range obj.Elements {
+			value, err := toGo(element, visiting)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+
+		return values, nil
+
+	case *object.Hash:
+		values := make(map[string]interface{}, obj.Len())
+		for _, pair := range obj.Pairs() {
+			value, err := toGo(pair.Value, visiting)
+			if err != nil {
+				return nil, err
+			}
+
+			key := pair.Key.Inspect()
+			if str, ok := pair.Key.(*object.String); ok {
+				key = str.Value
+			}
+
+			if _, ok := values[key]; ok {
+				return nil, fmt.Errorf("cannot convert HASH with several keys which are converted to %q to Go value", key)
+			}
+			values[key] = value
+		}
+
+		return values, nil
+
+	case *object.Module:
+		names := obj.ExportNames()
+		values := make(map[string]interface{}, len(names))
+		for _, name := range names {
+			export, _ := obj.Export(name)
+			value, err := toGo(export, visiting)
+			if err != nil {
+				return nil, err
+			}
+			values[name] = value
+		}
+
+		return values, nil
+
+	case *object.Function, *object.BuiltIn:
+		return func(args ...interface{}) (interface{}, error) {
+			return callObject(obj, args)
+		}, nil
+
+	case *object.Error:
+		return nil, &RuntimeError{Err: obj}
+
+	default:
+		return nil, fmt.Errorf("cannot convert %s to Go value", obj.Type())
+	}
+}
+
+// functionToBuiltIn returns built in function which converts arguments to types of parameters of Go function
+func functionToBuiltIn(fn reflect.Value) (*object.BuiltIn, error) {
+	fnType := fn.Type()
+
+	switch {
+	case fnType.NumOut() > 2,
+		fnType.NumOut() == 2 && fnType.Out(1) != errorType:
+		return nil, fmt.Errorf("cannot convert %s to built in function: results must be (value), (value, error) or (error)", fnType)
+	}
+
+	params := make([]string, fnType.NumIn())
+	for i := range params {
+		params[i] = typeName(parameterType(fnType, i))
+	}
+
+	// variadic parameter may be omitted like in Go
+	optional := 0
+	if fnType.IsVariadic() {
+		optional = 1
+	}
+
+	return &object.BuiltIn{Params: params, Optional: optional, Variadic: fnType.IsVariadic(), Fn: func(args ...object.Object) object.Object {
+		numIn := fnType.NumIn()
+		if fnType.IsVariadic() {
+			if len(args) < numIn-1 {
+				return evaluator.NewError("wrong number of arguments. got=%d, want=%d+", len(args), numIn-1)
+			}
+		} else if len(args) != numIn {
+			return evaluator.NewError("wrong number of arguments. got=%d, want=%d", len(args), numIn)
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			paramType := parameterType(fnType, i)
+
+			value, err := toGoType(arg, paramType)
+			if err != nil {
+				return evaluator.NewError("argument %d: %s", i+1, err)
+			}
+			in[i] = value
+		}
+
+		return resultsToObject(fn.Call(in))
+	}}, nil
+}
+
+func parameterType(fnType reflect.Type, i int) reflect.Type {
+	if fnType.IsVariadic() && i >= fnType.NumIn()-1 {
+		return fnType.In(fnType.NumIn() - 1).Elem()
+	}
+
+	return fnType.In(i)
+}
+
+// typeName returns type of objects which are converted to values of Go type t
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return object.BooleanObj
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return object.IntegerObj
+	case reflect.Float32, reflect.Float64:
+		return "INTEGER|FLOAT"
+	case reflect.String:
+		return object.StringObj
+	case reflect.Slice, reflect.Array:
+		return object.ArrayObj
+	case reflect.Map:
+		return object.HashObj
+	case reflect.Func:
+		return object.FunctionObj
+	default:
+		return "ANY"
+	}
+}
+
+// resultsToObject converts results of Go function to object, not nil error becomes error object
+func resultsToObject(results []reflect.Value) object.Object {
+	if len(results) > 0 {
+		last := results[len(results)-1]
+		if last.Type() == errorType {
+			if !last.IsNil() {
+				return evaluator.NewError("%s", last.Interface().(error))
+			}
+			results = results[:len(results)-1]
+		}
+	}
+
+	if len(results) == 0 {
+		return evaluator.NULL
+	}
+
+	obj, err := ToObject(results[0].Interface())
+	if err != nil {
+		return evaluator.NewError("%s", err)
+	}
+
+	return obj
+}
+
+// toGoType converts object to value of Go type t, parameters of object types receive objects as is
+func toGoType(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if t != emptyInterfaceType && reflect.TypeOf(obj).AssignableTo(t) {
+		return reflect.ValueOf(obj), nil
+	}
+
+	value, err := ToGo(obj)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), nil
+		default:
+			return reflect.Value{}, fmt.Errorf("cannot use %s as %s", obj.Type(), t)
+		}
+	}
+
+	return convertValue(reflect.ValueOf(value), obj, t)
+}
+
+func convertValue(v reflect.Value, obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+
+	mismatch := fmt.Errorf("cannot use %s as %s", obj.Type(), t)
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Kind() != reflect.Int64 || reflect.Zero(t).OverflowInt(v.Int()) {
+			return reflect.Value{}, mismatch
+		}
+		return v.Convert(t), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Kind() != reflect.Int64 || v.Int() < 0 || reflect.Zero(t).OverflowUint(uint64(v.Int())) {
+			return reflect.Value{}, mismatch
+		}
+		return reflect.ValueOf(uint64(v.Int())).Convert(t), nil
+
+	case reflect.Float32, reflect.Float64:
+		if v.Kind() != reflect.Int64 && v.Kind() != reflect.Float64 {
+			return reflect.Value{}, mismatch
+		}
+		return v.Convert(t), nil
+
+	case reflect.String, reflect.Bool:
+		if v.Kind() != t.Kind() {
+			return reflect.Value{}, mismatch
+		}
+		return v.Convert(t), nil
+
+	case reflect.Slice:
+		array, ok := obj.(*object.Array)
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+
+		slice := reflect.MakeSlice(t, len(array.Elements), len(array.Elements))
+		for i, element := range array.Elements {
+			value, err := toGoType(element, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			slice.Index(i).Set(value)
+		}
+
+		return slice, nil
+
+	case reflect.Map:
+		hash, ok := obj.(*object.Hash)
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+
+		m := reflect.MakeMapWithSize(t, hash.Len())
+		for _, pair := range hash.Pairs() {
+			key, err := toGoType(pair.Key, t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			value, err := toGoType(pair.Value, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			m.SetMapIndex(key, value)
+		}
+
+		return m, nil
+
+	default:
+		return reflect.Value{}, mismatch
+	}
+}
@@ -0,0 +1,174 @@
+// Package pukiclang provides API for embedding pukiclang interpreter into Go applications
+package pukiclang
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ythosa/pukiclang/src/evaluator"
+	"github.com/ythosa/pukiclang/src/lexer"
+	"github.com/ythosa/pukiclang/src/object"
+	"github.com/ythosa/pukiclang/src/parser"
+)
+
+// Interpreter is type for interpreter which keeps global variables between runs of programs,
+// it must not be used from several goroutines at the same time
+type Interpreter struct {
+	env    *object.Environment
+	limits evaluator.Limits
+}
+
+// New returns new interpreter with empty global environment and its own registry of standard built in functions
+func New() *Interpreter {
+	env := object.NewEnvironment()
+	env.SetBuiltIns(evaluator.NewBuiltIns())
+
+	return &Interpreter{env: env}
+}
+
+// BuiltIns returns registry of built in functions of the interpreter,
+// functions may be added, replaced or removed from it between runs of programs
+func (i *Interpreter) BuiltIns() *object.Registry {
+	return i.env.BuiltIns()
+}
+
+// SetLimits sets limits of execution of programs and functions which are run by the interpreter,
+// a program which exceeds them is stopped with runtime error of LimitError type
+func (i *Interpreter) SetLimits(limits evaluator.Limits) {
+	i.limits = limits
+}
+
+// SyntaxError is type for error of source which cannot be parsed
+type SyntaxError struct {
+	Diagnostics []parser.Diagnostic
+}
+
+// Error returns diagnostics of the parser separated by new lines
+func (e *SyntaxError) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// RuntimeError is type for error which is raised and not caught by the program
+type RuntimeError struct {
+	Err *object.Error
+}
+
+// Error returns message of the error with its stack trace
+func (e *RuntimeError) Error() string {
+	return e.Err.Traceback()
+}
+
+// Run parses and evaluates source in global environment of the interpreter,
+// it returns result of the last statement converted to Go value.
+// Evaluation is stopped with runtime error of LimitError type when context is done
+func (i *Interpreter) Run(ctx context.Context, source string) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.New(source))
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &SyntaxError{Diagnostics: p.Errors()}
+	}
+
+	return resultToGo(evaluator.EvalContext(ctx, program, i.env, i.limits))
+}
+
+// Call calls function which is defined in global environment with arguments converted from Go values,
+// it returns result converted to Go value
+func (i *Interpreter) Call(fnName string, args ...interface{}) (interface{}, error) {
+	fn, ok := i.env.Get(fnName)
+	if !ok {
+		return nil, fmt.Errorf("undefined function: %s", fnName)
+	}
+
+	objects, err := argsToObjects(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return resultToGo(evaluator.CallFunctionContext(context.Background(), fn, objects, i.limits))
+}
+
+// Set defines global variable with value converted from Go value
+func (i *Interpreter) Set(name string, value interface{}) error {
+	obj, err := ToObject(value)
+	if err != nil {
+		return err
+	}
+
+	i.env.Set(name, obj)
+
+	return nil
+}
+
+// Get returns value of global variable converted to Go value and is the variable defined
+func (i *Interpreter) Get(name string) (interface{}, bool, error) {
+	obj, ok := i.env.Get(name)
+	if !ok {
+		return nil, false, nil
+	}
+
+	value, err := ToGo(obj)
+
+	return value, true, err
+}
+
+// Register adds built in function which calls Go function fn to registry of the interpreter,
+// name may be qualified with namespace, e.g. "text.upper". Arguments are converted to types of parameters of fn.
+// Results of fn may be empty, one value, or one value and error, not nil error is raised in the program as runtime error.
+// Programs compiled for the vm see registered functions when BuiltIns is passed to SetBuiltIns of the compiler
+func (i *Interpreter) Register(name string, fn interface{}) error {
+	if reflect.TypeOf(fn) == nil || reflect.TypeOf(fn).Kind() != reflect.Func || reflect.ValueOf(fn).IsNil() {
+		return fmt.Errorf("cannot register %T as function", fn)
+	}
+
+	builtIn, err := functionToBuiltIn(reflect.ValueOf(fn))
+	if err != nil {
+		return err
+	}
+	builtIn.Name = name
+
+	return i.env.BuiltIns().Register(builtIn)
+}
+
+// callObject calls function object with arguments converted from Go values
+func callObject(fn object.Object, args []interface{}) (interface{}, error) {
+	objects, err := argsToObjects(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return resultToGo(evaluator.CallFunction(fn, objects))
+}
+
+func argsToObjects(args []interface{}) ([]object.Object, error) {
+	objects := make([]object.Object, len(args))
+	for i, arg := range args {
+		obj, err := ToObject(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %s", i+1, err)
+		}
+
+		objects[i] = obj
+	}
+
+	return objects, nil
+}
+
+func resultToGo(result object.Object) (interface{}, error) {
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, &RuntimeError{Err: errObj}
+	}
+
+	return ToGo(result)
+}
@@ -0,0 +1,318 @@
+package pukiclang_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ythosa/pukiclang/src/compiler"
+	"github.com/ythosa/pukiclang/src/evaluator"
+	"github.com/ythosa/pukiclang/src/lexer"
+	"github.com/ythosa/pukiclang/src/object"
+	"github.com/ythosa/pukiclang/src/parser"
+	"github.com/ythosa/pukiclang/src/pukiclang"
+	"github.com/ythosa/pukiclang/src/vm"
+)
+
+func TestRun(t *testing.T) {
+	interpreter := pukiclang.New()
+
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`1 + 2`, int64(3)},
+		{`1.5 * 2`, 3.0},
+		{`"a" + "b"`, "ab"},
+		{`1 < 2`, true},
+		{`if (false) { 1 }`, nil},
+		{`[1, "two", [true]]`, []interface{}{int64(1), "two", []interface{}{true}}},
+		{`{"a": 1, 2: "b"}`, map[string]interface{}{"a": int64(1), "2": "b"}},
+		{`2 ** 64`, new(big.Int).Lsh(big.NewInt(1), 64)},
+		{`let x = 10;`, nil},
+		{`x * 2`, int64(20)},
+	}
+
+	for _, tt := range tests {
+		result, err := interpreter.Run(context.Background(), tt.source)
+		if err != nil {
+			t.Errorf("error for %q: %s", tt.source, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("wrong result for %q. expected=%#v, got=%#v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	interpreter := pukiclang.New()
+
+	_, err := interpreter.Run(context.Background(), `let x = ;`)
+	var syntaxErr *pukiclang.SyntaxError
+	if !errors.As(err, &syntaxErr) || len(syntaxErr.Diagnostics) != 1 {
+		t.Errorf("expected syntax error. got=%v", err)
+	}
+
+	_, err = interpreter.Run(context.Background(), "let f = fn() { 1 / 0 };\nf()")
+	var runtimeErr *pukiclang.RuntimeError
+	if !errors.As(err, &runtimeErr) || err.Error() != "Error: 1:18: division by zero\n\tat f (called at 2:2)" {
+		t.Errorf("expected runtime error. got=%v", err)
+	}
+
+	_, err = interpreter.Run(context.Background(), "let a = [0]; a[0] = a; a")
+	if err == nil || err.Error() != "cannot convert ARRAY which contains itself to Go value" {
+		t.Errorf("expected conversion error. got=%v", err)
+	}
+
+	_, err = interpreter.Run(context.Background(), `{1: "a", "1": "b"}`)
+	if err == nil || err.Error() != `cannot convert HASH with several keys which are converted to "1" to Go value` {
+		t.Errorf("expected conversion error of colliding keys. got=%v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = interpreter.Run(ctx, `1`); err != context.Canceled {
+		t.Errorf("expected context error. got=%v", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	interpreter := pukiclang.New()
+	interpreter.SetLimits(evaluator.Limits{MaxSteps: 1000})
+
+	_, err := interpreter.Run(context.Background(), `let spin = fn() { while (true) { } }; spin()`)
+	var runtimeErr *pukiclang.RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Err.Kind != "LimitError" {
+		t.Fatalf("expected limit error. got=%v", err)
+	}
+
+	_, err = interpreter.Call("spin")
+	if !errors.As(err, &runtimeErr) || runtimeErr.Err.Message != "step limit exceeded: 1000" {
+		t.Errorf("expected limit error of call. got=%v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	interpreter.SetLimits(evaluator.Limits{})
+	_, err = interpreter.Run(ctx, `spin()`)
+	if !errors.As(err, &runtimeErr) || runtimeErr.Err.Message != "timeout exceeded" {
+		t.Errorf("expected timeout error. got=%v", err)
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	interpreter := pukiclang.New()
+
+	err := interpreter.Set("config", map[string]interface{}{
+		"limit": 10,
+		"ratio": float32(0.5),
+		"tags":  []string{"a", "b"},
+		"owner": nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := interpreter.Run(context.Background(),
+		`let total = config["limit"] * config["ratio"] + len(config["tags"]); config["owner"]`)
+	if err != nil || result != nil {
+		t.Fatalf("wrong result. got=%v, %v", result, err)
+	}
+
+	total, ok, err := interpreter.Get("total")
+	if !ok || err != nil || total != 7.0 {
+		t.Errorf("wrong value of total. got=%v, %v, %v", total, ok, err)
+	}
+
+	if _, ok, _ := interpreter.Get("missing"); ok {
+		t.Errorf("missing variable is defined")
+	}
+
+	if err := interpreter.Set("channel", make(chan int)); err == nil {
+		t.Errorf("expected error for unsupported value")
+	}
+}
+
+func TestCall(t *testing.T) {
+	interpreter := pukiclang.New()
+
+	_, err := interpreter.Run(context.Background(), `
+let add = fn(a, b) { a + b };
+let twice = fn(f, x) { f(f(x)) };
+let fail = fn() { throw "failed" };
+let adder = fn(n) { fn(x) { x + n } };`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := interpreter.Call("add", 1, 2)
+	if err != nil || result != int64(3) {
+		t.Errorf("wrong result of add. got=%v, %v", result, err)
+	}
+
+	result, err = interpreter.Call("twice", func(x int) int { return x * 3 }, 2)
+	if err != nil || result != int64(18) {
+		t.Errorf("wrong result of twice. got=%v, %v", result, err)
+	}
+
+	if _, err = interpreter.Call("fail"); err == nil || err.Error() != "Error: 4:19: failed\n\tat fail (called at -)" {
+		t.Errorf("wrong error of fail. got=%v", err)
+	}
+
+	if _, err = interpreter.Call("missing"); err == nil || err.Error() != "undefined function: missing" {
+		t.Errorf("wrong error of missing function. got=%v", err)
+	}
+
+	adder, err := interpreter.Call("adder", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addTen, ok := adder.(func(args ...interface{}) (interface{}, error))
+	if !ok {
+		t.Fatalf("result is not a function. got=%T", adder)
+	}
+
+	if result, err = addTen(5); err != nil || result != int64(15) {
+		t.Errorf("wrong result of returned function. got=%v, %v", result, err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	interpreter := pukiclang.New()
+
+	functions := map[string]interface{}{
+		"upper": strings.ToUpper,
+		"divide": func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, errors.New("cannot divide by zero")
+			}
+			return a / b, nil
+		},
+		"mean": func(values ...float64) float64 {
+			total := 0.0
+			for _, v := range values {
+				total += v
+			}
+			return total / float64(len(values))
+		},
+		"keys": func(m map[string]int) []string {
+			var keys []string
+			for k := range m {
+				keys = append(keys, k)
+			}
+			return keys
+		},
+		"kind":  func(obj object.Object) string { return string(obj.Type()) },
+		"log":   func(string) {},
+		"count": func(args ...interface{}) int { return len(args) },
+	}
+
+	for name, fn := range functions {
+		if err := interpreter.Register(name, fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`upper("abc")`, "ABC"},
+		{`divide(7, 2)`, int64(3)},
+		{`mean(1, 2.5, 3)`, 2.1666666666666665},
+		{`keys({"a": 1})`, []interface{}{"a"}},
+		{`kind([1])`, "ARRAY"},
+		{`log("message")`, nil},
+		{`count()`, int64(0)},
+		{`count(1, "a")`, int64(2)},
+	}
+
+	for _, tt := range tests {
+		result, err := interpreter.Run(context.Background(), tt.source)
+		if err != nil {
+			t.Errorf("error for %q: %s", tt.source, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("wrong result for %q. expected=%#v, got=%#v", tt.source, tt.expected, result)
+		}
+	}
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{`divide(1, 0)`, "Error: 1:7: cannot divide by zero"},
+		{`upper(1)`, "Error: 1:6: argument 1: cannot use INTEGER as string"},
+		{`upper()`, "Error: 1:6: wrong number of arguments. got=0, want=1"},
+		{`divide(2 ** 64, 1)`, "Error: 1:7: argument 1: cannot use BIGINT as int"},
+		{`keys({"a": "b"})`, "Error: 1:5: argument 1: cannot use STRING as int"},
+	}
+
+	for _, tt := range errorTests {
+		_, err := interpreter.Run(context.Background(), tt.source)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%v", tt.source, tt.expected, err)
+		}
+	}
+
+	if err := interpreter.Register("notFunction", 1); err == nil {
+		t.Errorf("expected error for registering not a function")
+	}
+
+	if err := interpreter.Register("badResults", func() (int, int) { return 1, 2 }); err == nil {
+		t.Errorf("expected error for function with unsupported results")
+	}
+}
+
+func TestBuiltIns(t *testing.T) {
+	interpreter := pukiclang.New()
+	other := pukiclang.New()
+
+	if err := interpreter.Register("text.repeat", strings.Repeat); err != nil {
+		t.Fatal(err)
+	}
+	interpreter.BuiltIns().Remove("puts")
+
+	result, err := interpreter.Run(context.Background(), `text.repeat("ab", 2)`)
+	if err != nil || result != "abab" {
+		t.Errorf("wrong result of namespaced function. got=%v, %v", result, err)
+	}
+
+	result, err = interpreter.Run(context.Background(), `find(builtins(), fn(b) { b["name"] == "text.repeat" })["signature"]`)
+	if err != nil || result != "text.repeat(STRING, INTEGER)" {
+		t.Errorf("wrong signature. got=%v, %v", result, err)
+	}
+
+	if _, err = interpreter.Run(context.Background(), `puts(1)`); err == nil {
+		t.Errorf("expected error for removed built in function")
+	}
+
+	if _, err = other.Run(context.Background(), `text.repeat("ab", 2)`); err == nil {
+		t.Errorf("built in function is registered in other interpreter")
+	}
+
+	comp := compiler.New()
+	comp.SetBuiltIns(interpreter.BuiltIns())
+	if err := comp.Compile(parser.New(lexer.New(`text.repeat("ab", 2)`)).ParseProgram()); err != nil {
+		t.Fatal(err)
+	}
+
+	machine := vm.New(comp.Bytecode())
+	if err := machine.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if str, ok := machine.Result().(*object.String); !ok || str.Value != "abab" {
+		t.Errorf("wrong result of registered function in vm. got=%v", machine.Result())
+	}
+}
@@ -0,0 +1,156 @@
+package repl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/ythosa/pukiclang/src/lexer"
+	"github.com/ythosa/pukiclang/src/object"
+	"github.com/ythosa/pukiclang/src/token"
+)
+
+const commandPrefix = ":"
+
+// command is type for REPL meta-command which is called with the rest of the line
+type command struct {
+	usage       string
+	description string
+	run         func(s *session, arg string)
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"help": {
+			usage:       ":help",
+			description: "show this help",
+			run:         (*session).help,
+		},
+		"env": {
+			usage:       ":env",
+			description: "list bindings of the session environment",
+			run:         (*session).listEnv,
+		},
+		"load": {
+			usage:       ":load <file>",
+			description: "evaluate file into the session environment",
+			run:         (*session).load,
+		},
+		"reset": {
+			usage:       ":reset",
+			description: "start with a fresh environment",
+			run:         (*session).reset,
+		},
+		"ast": {
+			usage:       ":ast <code>",
+			description: "print parsed AST of the code",
+			run:         (*session).printAST,
+		},
+		"tokens": {
+			usage:       ":tokens <code>",
+			description: "print tokens of the code",
+			run:         (*session).printTokens,
+		},
+		"type": {
+			usage:       ":type <code>",
+			description: "evaluate code and print type of the result",
+			run:         (*session).printType,
+		},
+	}
+}
+
+func (s *session) runCommand(line string) {
+	line = strings.TrimPrefix(line, commandPrefix)
+
+	name, arg := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		name, arg = line[:i], strings.TrimSpace(line[i+1:])
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		s.printf("unknown command: %s%s, type %shelp for the list of commands\n",
+			commandPrefix, name, commandPrefix)
+		return
+	}
+
+	cmd.run(s, arg)
+}
+
+func (s *session) printf(format string, a ...interface{}) {
+	_, _ = fmt.Fprintf(s.out, format, a...)
+}
+
+func (s *session) help(string) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s.printf("%-16s %s\n", commands[name].usage, commands[name].description)
+	}
+}
+
+func (s *session) listEnv(string) {
+	for _, name := range s.env.Names() {
+		value, _ := s.env.Get(name)
+		s.printf("%s: %s = %s\n", name, value.Type(), value.Inspect())
+	}
+}
+
+func (s *session) load(filename string) {
+	if filename == "" {
+		s.printf("usage: %s\n", commands["load"].usage)
+		return
+	}
+
+	source, err := ioutil.ReadFile(filename)
+	if err != nil {
+		s.printf("%s\n", err)
+		return
+	}
+
+	evaluated := s.eval(lexer.NewFile(filename, string(source)))
+	if errObj, ok := evaluated.(*object.Error); ok {
+		s.printf("%s\n", errObj.Traceback())
+	}
+}
+
+func (s *session) reset(string) {
+	s.env = object.NewEnvironment()
+}
+
+func (s *session) printAST(code string) {
+	program, ok := s.parse(lexer.New(code))
+	if !ok {
+		return
+	}
+
+	s.printf("%s\n", program.String())
+}
+
+func (s *session) printTokens(code string) {
+	l := lexer.New(code)
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		s.printf("%s\t%s\t%q\n", tok.Pos, tok.Type, tok.Literal)
+	}
+}
+
+func (s *session) printType(code string) {
+	evaluated := s.eval(lexer.New(code))
+
+	switch evaluated := evaluated.(type) {
+	case nil:
+		return
+	case *object.Error:
+		s.printf("%s\n", evaluated.Inspect())
+	default:
+		s.printf("%s\n", evaluated.Type())
+	}
+}
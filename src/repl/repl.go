@@ -2,50 +2,129 @@ package repl
 
 import (
 	"bufio"
-	"fmt"
 	"io"
+	"strings"
 
+	"github.com/ythosa/pukiclang/src/ast"
 	"github.com/ythosa/pukiclang/src/object"
+	"github.com/ythosa/pukiclang/src/token"
 
 	"github.com/ythosa/pukiclang/src/evaluator"
 	"github.com/ythosa/pukiclang/src/lexer"
 	"github.com/ythosa/pukiclang/src/parser"
 )
 
-const prompt = ">> "
+const (
+	prompt             = ">> "
+	continuationPrompt = ".. "
+)
 
 // Start starts REPL
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+	s := &session{
+		out: out,
+		env: object.NewEnvironment(),
+	}
+
+	var lines []string
 
 	for {
-		fmt.Print(prompt)
+		if len(lines) == 0 {
+			_, _ = io.WriteString(out, prompt)
+		} else {
+			_, _ = io.WriteString(out, continuationPrompt)
+		}
+
 		scanned := scanner.Scan()
 		if !scanned {
 			return
 		}
 
 		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
 
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		if len(lines) == 0 && strings.HasPrefix(line, commandPrefix) {
+			s.runCommand(line)
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			_, _ = io.WriteString(out, evaluated.Inspect())
-			_, _ = io.WriteString(out, "\n")
+		// empty line in continuation mode forces evaluation of incomplete input
+		if len(lines) == 0 || line != "" {
+			lines = append(lines, line)
+			if isIncomplete(strings.Join(lines, "\n")) {
+				continue
+			}
 		}
+
+		input := strings.Join(lines, "\n")
+		lines = nil
+
+		s.evaluate(lexer.New(input))
 	}
 }
 
-func printParserErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		_, _ = io.WriteString(out, "\t"+msg+"\n")
+// session is type for state of REPL which is shared between inputs
+type session struct {
+	out io.Writer
+	env *object.Environment
+}
+
+func (s *session) evaluate(l *lexer.Lexer) {
+	evaluated := s.eval(l)
+	if errObj, ok := evaluated.(*object.Error); ok {
+		_, _ = io.WriteString(s.out, errObj.Traceback())
+		_, _ = io.WriteString(s.out, "\n")
+	} else if evaluated != nil {
+		_, _ = io.WriteString(s.out, evaluated.Inspect())
+		_, _ = io.WriteString(s.out, "\n")
+	}
+}
+
+// eval parses and evaluates program in session environment, it returns nil on parser errors
+func (s *session) eval(l *lexer.Lexer) object.Object {
+	program, ok := s.parse(l)
+	if !ok {
+		return nil
+	}
+
+	return evaluator.Eval(program, s.env)
+}
+
+func (s *session) parse(l *lexer.Lexer) (*ast.Program, bool) {
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, p.Errors())
+		return nil, false
+	}
+
+	return program, true
+}
+
+// isIncomplete returns true if input has unclosed braces, brackets, parens, strings or block comments
+func isIncomplete(input string) bool {
+	l := lexer.New(input)
+	depth := 0
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
+		case token.ILLEGAL:
+			if strings.HasPrefix(tok.Literal, `"`) || strings.HasPrefix(tok.Literal, "/*") {
+				return true
+			}
+		}
+	}
+
+	return depth > 0
+}
+
+func printParserErrors(out io.Writer, errors []parser.Diagnostic) {
+	for _, d := range errors {
+		_, _ = io.WriteString(out, "\t"+d.Error()+"\n")
 	}
 }
@@ -0,0 +1,83 @@
+package repl_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ythosa/pukiclang/src/repl"
+)
+
+func TestMultiLineInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let add = fn(a, b) {\n  a + b\n};\nadd(1, 2)\n",
+			">> .. .. >> 3\n>> ",
+		},
+		{
+			"let h = {\n\"a\": [1,\n2]};\nh[\"a\"]\n",
+			">> .. .. >> [1, 2]\n>> ",
+		},
+		{
+			"\"multi\nline\"\n",
+			">> .. multi\nline\n>> ",
+		},
+		{
+			"/* sum\n{ of */ 1 + 2 // result {\n",
+			">> .. 3\n>> ",
+		},
+		{
+			"(1 +\n\n2\n",
+			">> .. \t1:5: unexpected end of input, expected expression\n>> 2\n>> ",
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		repl.Start(strings.NewReader(tt.input), &out)
+
+		if out.String() != tt.expected {
+			t.Errorf("wrong output. expected=%q, got=%q", tt.expected, out.String())
+		}
+	}
+}
+
+func TestMetaCommands(t *testing.T) {
+	file, err := ioutil.TempFile("", "*.puki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	_, _ = file.WriteString("let double = fn(x) { x * 2 };")
+	_ = file.Close()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":type 1 + 2", "INTEGER\n"},
+		{":type \"a\"", "STRING\n"},
+		{":ast 1 + 2 * 3", "(1 + (2 * 3))\n"},
+		{":tokens let x", "1:1\tLET\t\"let\"\n1:5\tIDENT\t\"x\"\n"},
+		{"let a = 5;\n:env", "a: INTEGER = 5\n"},
+		{"let a = 5;\n:reset\n:env", ""},
+		{":load " + file.Name() + "\ndouble(4)", "8\n"},
+		{":unknown", "unknown command: :unknown, type :help for the list of commands\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		repl.Start(strings.NewReader(tt.input), &out)
+
+		output := strings.ReplaceAll(out.String(), ">> ", "")
+		if output != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expected, output)
+		}
+	}
+}
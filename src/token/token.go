@@ -1,12 +1,45 @@
 package token
 
+import "fmt"
+
 // Type is type of token
 type Type string
 
-// Token is type for token which contains type and literal
+// Token is type for token which contains type, literal and position in source code
 type Token struct {
 	Type    Type
-	Literal string // todo: in the feature need to contain number of string, filename, etc.
+	Literal string
+	Pos     Position
+}
+
+// Position is type for position of token in source code
+type Position struct {
+	Filename string // name of source file, may be empty
+	Offset   int    // byte offset, starting at 0
+	Line     int    // line number, starting at 1
+	Column   int    // column number, starting at 1
+}
+
+// IsValid returns true if position is set
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns string representation of position in `file:line:column` format
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+
+		return "-"
+	}
+
+	if p.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+	}
+
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 // Names of tokens
@@ -17,8 +50,11 @@ const (
 	// Identifiers + literals
 	IDENT  = "IDENT" // add, foobar, x, y, ...
 	INT    = "INT"   // 1343456
+	FLOAT  = "FLOAT" // 3.14, 1e9, .5
 	STRING = "STRING"
 
+	COMMENT = "COMMENT" // line comment or block comment, returned only if lexer emits comments
+
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -26,6 +62,8 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
+	POWER    = "**"
 
 	LT = "<"
 	GT = ">"
@@ -35,10 +73,20 @@ const (
 	LTEQ  = "<="
 	GTEQ  = ">="
 
+	AND = "&&"
+	OR  = "||"
+
+	PLUSASSIGN     = "+="
+	MINUSASSIGN    = "-="
+	ASTERISKASSIGN = "*="
+	SLASHASSIGN    = "/="
+	PERCENTASSIGN  = "%="
+
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	DOT       = "."
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -55,16 +103,38 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
 )
 
 var keywords = map[string]Type{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"import":   IMPORT,
+	"export":   EXPORT,
 }
 
 // LookupIdent returns type of passed token (string)
@@ -0,0 +1,34 @@
+package vm
+
+import (
+	"github.com/ythosa/pukiclang/src/code"
+	"github.com/ythosa/pukiclang/src/object"
+	"github.com/ythosa/pukiclang/src/token"
+)
+
+// Frame is type for call frame of function which is being executed
+type Frame struct {
+	cl          *object.Closure
+	ip          int
+	opStart     int // offset of instruction which is being executed
+	basePointer int
+}
+
+// NewFrame returns new frame for passed closure
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{
+		cl:          cl,
+		ip:          -1,
+		basePointer: basePointer,
+	}
+}
+
+// Instructions returns instructions of the frame function
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
+
+// Pos returns position in source code of instruction which is being executed
+func (f *Frame) Pos() token.Position {
+	return f.cl.Fn.Positions[f.opStart]
+}
@@ -0,0 +1,782 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ythosa/pukiclang/src/code"
+	"github.com/ythosa/pukiclang/src/compiler"
+	"github.com/ythosa/pukiclang/src/evaluator"
+	"github.com/ythosa/pukiclang/src/object"
+)
+
+// Limits of the virtual machine, stack starts with StackSize slots and grows on demand up to MaxStackSize,
+// max number of frames allows the same depth of calls as the evaluator
+const (
+	StackSize    = 2048
+	MaxStackSize = 1 << 20
+	GlobalsSize  = 65536
+	MaxFrames    = evaluator.DefaultMaxCallDepth + 1
+)
+
+var infixOperators = map[code.Opcode]string{
+	code.OpAdd:          "+",
+	code.OpSub:          "-",
+	code.OpMul:          "*",
+	code.OpDiv:          "/",
+	code.OpMod:          "%",
+	code.OpPow:          "**",
+	code.OpEqual:        "==",
+	code.OpNotEqual:     "!=",
+	code.OpGreaterThan:  ">",
+	code.OpLessThan:     "<",
+	code.OpGreaterEqual: ">=",
+	code.OpLessEqual:    "<=",
+}
+
+var prefixOperators = map[code.Opcode]string{
+	code.OpBang:  "!",
+	code.OpMinus: "-",
+}
+
+// openUpvalue is type for upvalue which still points to the stack slot
+type openUpvalue struct {
+	slot    int
+	upvalue *object.Upvalue
+}
+
+// handler is type for error handler which is set up by try expression
+type handler struct {
+	address    int  // address of instruction which handles error
+	catch      bool // catch handler receives error as hash, finally handler receives error itself
+	frameIndex int  // index of frame which set up the handler
+	sp         int  // stack pointer at the moment of setting up the handler
+}
+
+// VM is type for virtual machine which executes bytecode
+type VM struct {
+	constants []object.Object
+
+	stack []object.Object
+	sp    int // always points to the next free slot, top of stack is stack[sp-1]
+
+	globals     []object.Object
+	globalNames []string
+
+	frames      []*Frame
+	framesIndex int
+
+	openUpvalues []openUpvalue
+	handlers     []handler
+
+	execution *object.Execution // execution which is passed to built in functions, nil if output is not set
+
+	result object.Object
+}
+
+// New returns new virtual machine for passed bytecode
+func New(bytecode *compiler.Bytecode) *VM {
+	return NewWithGlobals(bytecode, make([]object.Object, GlobalsSize))
+}
+
+// NewWithGlobals returns new virtual machine which uses passed globals store
+func NewWithGlobals(bytecode *compiler.Bytecode, globals []object.Object) *VM {
+	mainFn := &object.CompiledFunction{
+		Instructions: bytecode.Instructions,
+		Positions:    bytecode.Positions,
+	}
+	mainFrame := NewFrame(&object.Closure{Fn: mainFn}, 0)
+
+	frames := []*Frame{mainFrame}
+
+	return &VM{
+		constants:   bytecode.Constants,
+		stack:       make([]object.Object, StackSize),
+		sp:          0,
+		globals:     globals,
+		globalNames: bytecode.GlobalNames,
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+// SetOutput sets writer which values printed by the program are written to
+func (vm *VM) SetOutput(out io.Writer) {
+	vm.execution = &object.Execution{Output: out}
+}
+
+// Result returns value of the last evaluated top level statement or error which stopped execution
+func (vm *VM) Result() object.Object {
+	return vm.result
+}
+
+// Run executes bytecode
+func (vm *VM) Run() error {
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		errObj, err := vm.step()
+		if err != nil {
+			return err
+		}
+
+		if errObj != nil && !vm.handleError(errObj) {
+			vm.result = errObj
+			return nil
+		}
+
+		if vm.framesIndex == 0 {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// step executes the next instruction of the current frame, error object is located at the instruction
+func (vm *VM) step() (*object.Error, error) {
+	frame := vm.currentFrame()
+	frame.ip++
+	frame.opStart = frame.ip
+
+	ins := frame.Instructions()
+	op := code.Opcode(ins[frame.ip])
+
+	errObj, err := vm.execute(op, frame, ins)
+	if errObj != nil && !errObj.Pos.IsValid() {
+		errObj.Pos = vm.currentFrame().Pos()
+	}
+
+	return errObj, err
+}
+
+// callFunction calls function on behalf of built in function and executes instructions until the call returns.
+// Errors are handled only by try expressions inside the call, other errors are returned to built in function
+func (vm *VM) callFunction(fn object.Object, args ...object.Object) object.Object {
+	sp, frames, handlers := vm.sp, vm.framesIndex, len(vm.handlers)
+
+	if errObj := vm.push(fn); errObj != nil {
+		return errObj
+	}
+	for _, arg := range args {
+		if errObj := vm.push(arg); errObj != nil {
+			vm.sp = sp
+			return errObj
+		}
+	}
+
+	if errObj := vm.executeCall(len(args)); errObj != nil {
+		vm.sp = sp
+		return errObj
+	}
+
+	for vm.framesIndex > frames {
+		errObj, err := vm.step()
+		if err != nil {
+			errObj = evaluator.NewError("%s", err)
+		}
+
+		if errObj != nil && (len(vm.handlers) == handlers || !vm.handleError(errObj)) {
+			vm.unwindFrames(errObj, frames-1)
+			vm.sp = sp
+
+			return errObj
+		}
+	}
+
+	result := vm.pop()
+	vm.sp = sp
+
+	return result
+}
+
+// execute executes one instruction, it returns error object if execution must be stopped
+func (vm *VM) execute(op code.Opcode, frame *Frame, ins code.Instructions) (*object.Error, error) {
+	switch op {
+	case code.OpConstant:
+		constIndex := code.ReadUint16(ins[frame.ip+1:])
+		frame.ip += 2
+
+		return vm.push(vm.constants[constIndex]), nil
+
+	case code.OpPop:
+		popped := vm.pop()
+		if vm.framesIndex == 1 {
+			vm.result = popped
+		}
+
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+		code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan,
+		code.OpGreaterEqual, code.OpLessEqual:
+		right := vm.pop()
+		left := vm.pop()
+
+		return vm.pushResult(evaluator.InfixOperation(infixOperators[op], left, right)), nil
+
+	case code.OpBang, code.OpMinus:
+		right := vm.pop()
+
+		return vm.pushResult(evaluator.PrefixOperation(prefixOperators[op], right)), nil
+
+	case code.OpTrue:
+		return vm.push(evaluator.TRUE), nil
+
+	case code.OpFalse:
+		return vm.push(evaluator.FALSE), nil
+
+	case code.OpNull:
+		return vm.push(evaluator.NULL), nil
+
+	case code.OpJump:
+		pos := int(code.ReadUint16(ins[frame.ip+1:]))
+		frame.ip = pos - 1
+
+	case code.OpJumpNotTruthy:
+		pos := int(code.ReadUint16(ins[frame.ip+1:]))
+		frame.ip += 2
+
+		condition := vm.pop()
+		if !evaluator.IsTruthy(condition) {
+			frame.ip = pos - 1
+		}
+
+	case code.OpJumpNotTruthyOrPop, code.OpJumpTruthyOrPop:
+		pos := int(code.ReadUint16(ins[frame.ip+1:]))
+		frame.ip += 2
+
+		if evaluator.IsTruthy(vm.stack[vm.sp-1]) == (op == code.OpJumpTruthyOrPop) {
+			frame.ip = pos - 1
+		} else {
+			vm.pop()
+		}
+
+	case code.OpSetGlobal:
+		globalIndex := code.ReadUint16(ins[frame.ip+1:])
+		frame.ip += 2
+
+		vm.globals[globalIndex] = vm.pop()
+		if vm.framesIndex == 1 {
+			vm.result = nil
+		}
+
+	case code.OpGetGlobal:
+		globalIndex := code.ReadUint16(ins[frame.ip+1:])
+		frame.ip += 2
+
+		return vm.pushVariable(vm.globals[globalIndex], vm.globalNames[globalIndex]), nil
+
+	case code.OpSetLocal:
+		localIndex := code.ReadUint8(ins[frame.ip+1:])
+		frame.ip++
+
+		vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+
+	case code.OpGetLocal:
+		localIndex := code.ReadUint8(ins[frame.ip+1:])
+		frame.ip++
+
+		value := vm.stack[frame.basePointer+int(localIndex)]
+
+		return vm.pushVariable(value, frame.cl.Fn.LocalNames[localIndex]), nil
+
+	case code.OpAssignGlobal:
+		globalIndex := code.ReadUint16(ins[frame.ip+1:])
+		frame.ip += 2
+
+		return vm.assignVariable(&vm.globals[globalIndex], vm.globalNames[globalIndex]), nil
+
+	case code.OpAssignLocal:
+		localIndex := code.ReadUint8(ins[frame.ip+1:])
+		frame.ip++
+
+		slot := &vm.stack[frame.basePointer+int(localIndex)]
+
+		return vm.assignVariable(slot, frame.cl.Fn.LocalNames[localIndex]), nil
+
+	case code.OpAssignFree:
+		freeIndex := code.ReadUint8(ins[frame.ip+1:])
+		frame.ip++
+
+		upvalue := frame.cl.Free[freeIndex]
+
+		return vm.assignVariable(upvalue.Value, frame.cl.Fn.Captures[freeIndex].Name), nil
+
+	case code.OpDup2:
+		if errObj := vm.push(vm.stack[vm.sp-2]); errObj != nil {
+			return errObj, nil
+		}
+		return vm.push(vm.stack[vm.sp-2]), nil
+
+	case code.OpSetIndex:
+		value := vm.pop()
+		index := vm.pop()
+		left := vm.pop()
+
+		return vm.pushResult(evaluator.SetIndexOperation(left, index, value)), nil
+
+	case code.OpGetFree:
+		freeIndex := code.ReadUint8(ins[frame.ip+1:])
+		frame.ip++
+
+		upvalue := frame.cl.Free[freeIndex]
+
+		return vm.pushVariable(*upvalue.Value, frame.cl.Fn.Captures[freeIndex].Name), nil
+
+	case code.OpArray:
+		numElements := int(code.ReadUint16(ins[frame.ip+1:]))
+		frame.ip += 2
+
+		elements := make([]object.Object, numElements)
+		copy(elements, vm.stack[vm.sp-numElements:vm.sp])
+		vm.sp -= numElements
+
+		return vm.push(&object.Array{Elements: elements}), nil
+
+	case code.OpHash:
+		numElements := int(code.ReadUint16(ins[frame.ip+1:]))
+		frame.ip += 2
+
+		hash, errObj := vm.buildHash(vm.sp-numElements, vm.sp)
+		if errObj != nil {
+			return errObj, nil
+		}
+		vm.sp -= numElements
+
+		return vm.push(hash), nil
+
+	case code.OpLoadModule:
+		globalIndex := code.ReadUint16(ins[frame.ip+1:])
+		constIndex := code.ReadUint16(ins[frame.ip+3:])
+		frame.ip += 4
+
+		if module := vm.globals[globalIndex]; module != nil {
+			return vm.push(module), nil
+		}
+
+		if errObj, err := vm.pushClosure(int(constIndex)); errObj != nil || err != nil {
+			return errObj, err
+		}
+
+		return vm.executeCall(0), nil
+
+	case code.OpImportError:
+		constIndex := code.ReadUint16(ins[frame.ip+1:])
+		frame.ip += 2
+
+		return evaluator.NewError("%s", vm.constants[constIndex].(*object.String).Value), nil
+
+	case code.OpModule:
+		nameIndex := code.ReadUint16(ins[frame.ip+1:])
+		numElements := int(code.ReadUint16(ins[frame.ip+3:]))
+		frame.ip += 4
+
+		module := vm.buildModule(vm.constants[nameIndex].Inspect(), vm.sp-numElements, vm.sp)
+		vm.sp -= numElements
+
+		return vm.push(module), nil
+
+	case code.OpIndex:
+		index := vm.pop()
+		left := vm.pop()
+
+		return vm.pushResult(evaluator.IndexOperation(left, index)), nil
+
+	case code.OpCall:
+		numArgs := code.ReadUint8(ins[frame.ip+1:])
+		frame.ip++
+
+		return vm.executeCall(int(numArgs)), nil
+
+	case code.OpReturnValue:
+		returnValue := vm.pop()
+
+		return vm.returnFromFrame(returnValue), nil
+
+	case code.OpReturn:
+		return vm.returnFromFrame(evaluator.NULL), nil
+
+	case code.OpClosure:
+		constIndex := code.ReadUint16(ins[frame.ip+1:])
+		frame.ip += 2
+
+		return vm.pushClosure(int(constIndex))
+
+	case code.OpSetupCatch, code.OpSetupFinally:
+		address := int(code.ReadUint16(ins[frame.ip+1:]))
+		frame.ip += 2
+
+		vm.setupHandler(address, op == code.OpSetupCatch)
+
+	case code.OpPopHandler:
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+	case code.OpGetIter:
+		iterable := vm.pop()
+
+		return vm.pushResult(evaluator.Iterate(iterable)), nil
+
+	case code.OpIterNext:
+		address := int(code.ReadUint16(ins[frame.ip+1:]))
+		variables := code.ReadUint8(ins[frame.ip+3:])
+		frame.ip += 3
+
+		return vm.iterNext(address, int(variables)), nil
+
+	case code.OpThrow:
+		value := vm.pop()
+
+		// finally handler rethrows error which it has received
+		if errObj, ok := value.(*object.Error); ok {
+			return errObj, nil
+		}
+
+		return evaluator.ThrowOperation(value), nil
+
+	default:
+		def, err := code.Lookup(byte(op))
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("opcode %s is not supported", def.Name)
+	}
+
+	return nil, nil
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+// handleError unwinds frames up to the innermost error handler and passes error to it,
+// unwound calls are recorded in stack trace of error. It returns false if there is no handler
+func (vm *VM) handleError(errObj *object.Error) bool {
+	targetFrame := 0
+	if len(vm.handlers) > 0 {
+		targetFrame = vm.handlers[len(vm.handlers)-1].frameIndex
+	}
+
+	vm.unwindFrames(errObj, targetFrame)
+
+	if len(vm.handlers) == 0 {
+		return false
+	}
+
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+	vm.sp = h.sp
+	vm.currentFrame().ip = h.address - 1
+
+	if h.catch {
+		vm.push(evaluator.ErrorValue(errObj))
+	} else {
+		vm.push(errObj)
+	}
+
+	return true
+}
+
+// unwindFrames pops frames above target frame, popped calls are recorded in stack trace of error
+func (vm *VM) unwindFrames(errObj *object.Error, targetFrame int) {
+	for vm.framesIndex-1 > targetFrame {
+		frame := vm.popFrame()
+		vm.closeUpvalues(frame.basePointer)
+
+		errObj.Stack = append(errObj.Stack, object.StackFrame{
+			Function: frame.cl.Fn.Name,
+			Pos:      vm.currentFrame().Pos(),
+		})
+	}
+}
+
+// iterNext pushes values of the next iteration for loop variables or jumps to address if iteration is over
+func (vm *VM) iterNext(address int, variables int) *object.Error {
+	iterator := vm.pop().(*object.Iterator)
+
+	key, value, ok := iterator.Next()
+	if !ok {
+		vm.currentFrame().ip = address - 1
+		return nil
+	}
+
+	if variables == 2 {
+		if errObj := vm.push(key); errObj != nil {
+			return errObj
+		}
+		return vm.push(value)
+	}
+
+	if iterator.ByKey {
+		return vm.push(key)
+	}
+
+	return vm.push(value)
+}
+
+// setupHandler sets up error handler of the current frame
+func (vm *VM) setupHandler(address int, catch bool) {
+	vm.handlers = append(vm.handlers, handler{
+		address:    address,
+		catch:      catch,
+		frameIndex: vm.framesIndex - 1,
+		sp:         vm.sp,
+	})
+}
+
+func (vm *VM) pushFrame(f *Frame) *object.Error {
+	if vm.framesIndex >= MaxFrames {
+		return evaluator.NewError("stack overflow")
+	}
+
+	if vm.framesIndex == len(vm.frames) {
+		vm.frames = append(vm.frames, f)
+	} else {
+		vm.frames[vm.framesIndex] = f
+	}
+	vm.framesIndex++
+
+	return nil
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+func (vm *VM) push(o object.Object) *object.Error {
+	if errObj := vm.growStack(vm.sp + 1); errObj != nil {
+		return errObj
+	}
+
+	vm.stack[vm.sp] = o
+	vm.sp++
+
+	return nil
+}
+
+func (vm *VM) pop() object.Object {
+	o := vm.stack[vm.sp-1]
+	vm.sp--
+
+	return o
+}
+
+// pushResult pushes result of operation or returns it if it is an error
+func (vm *VM) pushResult(result object.Object) *object.Error {
+	if errObj, ok := result.(*object.Error); ok {
+		return errObj
+	}
+
+	return vm.push(result)
+}
+
+// assignVariable sets value on top of the stack to defined variable, the value stays on the stack
+func (vm *VM) assignVariable(variable *object.Object, name string) *object.Error {
+	if *variable == nil {
+		return evaluator.NewError("assignment to undefined variable: %s", name)
+	}
+
+	*variable = vm.stack[vm.sp-1]
+
+	return nil
+}
+
+// pushVariable pushes value of variable or returns error if variable is not defined yet
+func (vm *VM) pushVariable(value object.Object, name string) *object.Error {
+	if value == nil {
+		return evaluator.NewError("identifier not found: %s", name)
+	}
+
+	return vm.push(value)
+}
+
+func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, *object.Error) {
+	hash := object.NewHash()
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		if !hash.Set(key, value) {
+			return nil, evaluator.NewError("unusable as hash key: %s", key.Type())
+		}
+	}
+
+	return hash, nil
+}
+
+// buildModule returns module with exports from pairs of names and indexes of their globals on the stack
+func (vm *VM) buildModule(name string, startIndex, endIndex int) *object.Module {
+	bindings := &moduleGlobals{globals: vm.globals, indexes: make(map[string]int)}
+	names := make([]string, 0, (endIndex-startIndex)/2)
+
+	for i := startIndex; i < endIndex; i += 2 {
+		export := vm.stack[i].(*object.String).Value
+		bindings.indexes[export] = int(vm.stack[i+1].(*object.Integer).Value)
+		names = append(names, export)
+	}
+
+	return object.NewModule(name, names, bindings)
+}
+
+// moduleGlobals is type for exported variables of module which are stored in globals of the vm
+type moduleGlobals struct {
+	globals []object.Object
+	indexes map[string]int // indexes of globals by names of variables
+}
+
+// Get returns current value of variable of module
+func (m *moduleGlobals) Get(name string) (object.Object, bool) {
+	index, ok := m.indexes[name]
+	if !ok || m.globals[index] == nil {
+		return nil, false
+	}
+
+	return m.globals[index], true
+}
+
+func (vm *VM) executeCall(numArgs int) *object.Error {
+	callee := vm.stack[vm.sp-1-numArgs]
+
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+
+	case *object.BuiltIn:
+		args := vm.stack[vm.sp-numArgs : vm.sp : vm.sp] // built in function must not append to the stack
+
+		result := callee.Apply(vm.execution, vm.callFunction, args...)
+		vm.sp = vm.sp - numArgs - 1
+
+		if result == nil {
+			result = evaluator.NULL
+		}
+
+		return vm.pushResult(result)
+
+	default:
+		return evaluator.NewError("not a function: %s", callee.Type())
+	}
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) *object.Error {
+	if numArgs != cl.Fn.NumParameters {
+		return evaluator.NewError("wrong number of arguments: want=%d, got=%d",
+			cl.Fn.NumParameters, numArgs)
+	}
+
+	basePointer := vm.sp - numArgs
+	if errObj := vm.growStack(basePointer + cl.Fn.NumLocals + 1); errObj != nil {
+		return errObj
+	}
+
+	if errObj := vm.pushFrame(NewFrame(cl, basePointer)); errObj != nil {
+		return errObj
+	}
+
+	// locals which are not parameters must not keep values of previous frames
+	for i := basePointer + numArgs; i < basePointer+cl.Fn.NumLocals; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = basePointer + cl.Fn.NumLocals
+
+	return nil
+}
+
+func (vm *VM) returnFromFrame(returnValue object.Object) *object.Error {
+	if vm.framesIndex == 1 {
+		vm.result = returnValue
+		vm.framesIndex = 0
+
+		return nil
+	}
+
+	// handlers of try expressions which are left by return
+	for len(vm.handlers) > 0 && vm.handlers[len(vm.handlers)-1].frameIndex >= vm.framesIndex-1 {
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	}
+
+	frame := vm.popFrame()
+	vm.closeUpvalues(frame.basePointer)
+	vm.sp = frame.basePointer - 1
+
+	return vm.push(returnValue)
+}
+
+func (vm *VM) pushClosure(constIndex int) (*object.Error, error) {
+	fn, ok := vm.constants[constIndex].(*object.CompiledFunction)
+	if !ok {
+		return nil, fmt.Errorf("not a function: %+v", vm.constants[constIndex])
+	}
+
+	frame := vm.currentFrame()
+
+	free := make([]*object.Upvalue, len(fn.Captures))
+	for i, capture := range fn.Captures {
+		if capture.Local {
+			free[i] = vm.captureUpvalue(frame.basePointer + capture.Index)
+		} else {
+			free[i] = frame.cl.Free[capture.Index]
+		}
+	}
+
+	return vm.push(&object.Closure{Fn: fn, Free: free}), nil
+}
+
+// growStack makes stack at least size slots long, it returns error if size exceeds the limit
+func (vm *VM) growStack(size int) *object.Error {
+	if size <= len(vm.stack) {
+		return nil
+	}
+
+	if size > MaxStackSize {
+		return evaluator.NewError("stack overflow")
+	}
+
+	newSize := 2 * len(vm.stack)
+	for newSize < size {
+		newSize *= 2
+	}
+	if newSize > MaxStackSize {
+		newSize = MaxStackSize
+	}
+
+	stack := make([]object.Object, newSize)
+	copy(stack, vm.stack)
+	vm.stack = stack
+
+	// open upvalues must point to slots of the new stack
+	for _, open := range vm.openUpvalues {
+		open.upvalue.Value = &vm.stack[open.slot]
+	}
+
+	return nil
+}
+
+// captureUpvalue returns upvalue of stack slot, closures which capture the same slot share it
+func (vm *VM) captureUpvalue(slot int) *object.Upvalue {
+	for _, open := range vm.openUpvalues {
+		if open.slot == slot {
+			return open.upvalue
+		}
+	}
+
+	upvalue := &object.Upvalue{Value: &vm.stack[slot]}
+	vm.openUpvalues = append(vm.openUpvalues, openUpvalue{slot: slot, upvalue: upvalue})
+
+	return upvalue
+}
+
+// closeUpvalues closes upvalues of stack slots which are going to be released
+func (vm *VM) closeUpvalues(fromSlot int) {
+	open := vm.openUpvalues[:0]
+
+	for _, u := range vm.openUpvalues {
+		if u.slot >= fromSlot {
+			u.upvalue.Close()
+		} else {
+			open = append(open, u)
+		}
+	}
+
+	vm.openUpvalues = open
+}
@@ -0,0 +1,117 @@
+package vm_test
+
+import (
+	"testing"
+
+	"github.com/ythosa/pukiclang/src/compiler"
+	"github.com/ythosa/pukiclang/src/lexer"
+	"github.com/ythosa/pukiclang/src/object"
+	"github.com/ythosa/pukiclang/src/parser"
+	"github.com/ythosa/pukiclang/src/vm"
+)
+
+// Most of the language behaviour is tested against both backends in evaluator tests
+
+type vmTestCase struct {
+	input    string
+	expected interface{}
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			`let fib = fn(x) { if (x < 2) { x } else { fib(x - 1) + fib(x - 2) } }; fib(15)`,
+			610,
+		},
+		{
+			`let wrapper = fn() {
+				let countDown = fn(x) { if (x == 0) { return 0; } countDown(x - 1) };
+				countDown(10);
+			};
+			wrapper();`,
+			0,
+		},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			`let adder = fn(a) { fn(b) { fn(c) { a + b + c } } }; adder(1)(2)(3)`,
+			6,
+		},
+		{
+			// closure sees later redefinition of captured variable like the evaluator does
+			`let mk = fn() { let a = 1; let get = fn() { a }; let a = 2; get }; mk()()`,
+			2,
+		},
+		{
+			`let mk = fn(x) { let get = fn() { x }; let noise = fn(y) { y }; noise(100); get };
+			let a = mk(1); let b = mk(2); a() + b()`,
+			3,
+		},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`fn(a) { a }()`, "wrong number of arguments: want=1, got=0"},
+		{`let f = fn() { f() }; f()`, "stack overflow"},
+		{`fn() { let x = y; }()`, "identifier not found: y"},
+		{`1(2)`, "not a function: INTEGER"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func runVMTests(t *testing.T, tests []vmTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		machine := vm.New(comp.Bytecode())
+		if err := machine.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		testExpectedObject(t, tt.expected, machine.Result())
+	}
+}
+
+func testExpectedObject(t *testing.T, expected interface{}, actual object.Object) {
+	t.Helper()
+
+	switch expected := expected.(type) {
+	case int:
+		integer, ok := actual.(*object.Integer)
+		if !ok {
+			t.Errorf("object is not Integer. got=%T (%+v)", actual, actual)
+			return
+		}
+
+		if integer.Value != int64(expected) {
+			t.Errorf("object has wrong value. got=%d, want=%d", integer.Value, expected)
+		}
+
+	case string:
+		errObj, ok := actual.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error. got=%T (%+v)", actual, actual)
+			return
+		}
+
+		if errObj.Message != expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+		}
+	}
+}